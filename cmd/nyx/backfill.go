@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"runtime"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/block"
+	"github.com/kunalsinghdadhwal/nyx/internal/client"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func runBackfill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := fs.Uint64("from", 0, "first block number to index")
+	to := fs.Uint64("to", 0, "last block number to index (defaults to the chain head)")
+	workers := fs.Int("workers", runtime.NumCPU(), "number of blocks processed concurrently")
+	resume := fs.Bool("resume", true, "continue from the last saved checkpoint")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(ctx, &data.BlockChainNodeConn{RPC: client.RPC()})
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	go block.ProcessQueue(ctx, a.Node.RPC, a.DB, a.Publisher, a.Queue, a.Status)
+	go block.FollowFinality(ctx, a.Node.RPC, a.Queue)
+
+	if *to == 0 {
+		*to = a.Queue.StartedWith
+	}
+
+	if *from > *to {
+		return errors.New("--from must not be greater than --to")
+	}
+
+	if *workers < 1 {
+		return errors.New("--workers must be at least 1")
+	}
+
+	logger.S().Infof("Backfilling blocks %d to %d", *from, *to)
+
+	return block.SyncBlocksByRange(ctx, a.Node.RPC, a.DB, a.Publisher, a.Queue, a.Status, *from, *to, *workers, *resume)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/block"
+	"github.com/kunalsinghdadhwal/nyx/internal/client"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func runIndex(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := bootstrap(ctx, client.Connect())
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	go block.ProcessQueue(ctx, a.Node.RPC, a.DB, a.Publisher, a.Queue, a.Status)
+	go block.FollowFinality(ctx, a.Node.RPC, a.Queue)
+
+	logger.S().Infof("Indexing from block %d", a.Queue.StartedWith)
+
+	block.FollowHead(ctx, a.Node, a.Queue, a.Status)
+
+	logger.S().Info("Shutting down indexer")
+	return nil
+}
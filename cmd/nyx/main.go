@@ -1 +1,194 @@
-package nyx
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kunalsinghdadhwal/nyx/internal/client"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/publisher"
+	"github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const usage = `Usage: nyx <command> [flags]
+
+Commands:
+  index      follow the chain head and index new blocks
+  backfill   index a historical range of blocks (--from, --to)
+  serve      run the HTTP/WebSocket API
+  status     print indexing progress and exit
+`
+
+type app struct {
+	Node      *data.BlockChainNodeConn
+	Publisher data.Publisher
+	DB        *gorm.DB
+	Queue     *queue.BlockProcessorQueue
+	Status    *data.StatusHolder
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	logger.Init(os.Getenv("ENV"))
+	log := logger.S()
+	defer logger.L().Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+
+	switch os.Args[1] {
+	case "index":
+		err = runIndex(ctx, os.Args[2:])
+	case "backfill":
+		err = runBackfill(ctx, os.Args[2:])
+	case "serve":
+		err = runServe(ctx, os.Args[2:])
+	case "status":
+		err = runStatus(ctx, os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s: %s\n", os.Args[1], err.Error())
+	}
+}
+
+// newRedisInfo connects to Redis, publishing over Pub/Sub unless
+// REDIS_TRANSPORT asks for Streams, which REDIS_STREAM_MAXLEN caps the length
+// of.
+func newRedisInfo() *data.RedisInfo {
+	transport := envOr("REDIS_TRANSPORT", data.PubSubTransport)
+	if transport != data.PubSubTransport && transport != data.StreamsTransport {
+		logger.S().Fatalf("Unknown Redis transport %q, expected %q or %q\n", transport, data.PubSubTransport, data.StreamsTransport)
+	}
+
+	maxLen := int64(10000)
+	if v := os.Getenv("REDIS_STREAM_MAXLEN"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			logger.S().Fatalf("Bad REDIS_STREAM_MAXLEN %q\n", v)
+		}
+		maxLen = n
+	}
+
+	return &data.RedisInfo{
+		Client:                 client.Redis(),
+		BlockPublishTopic:      "block",
+		TxPublishTopic:         "transaction",
+		EventPublishTopic:      "event",
+		ReorgPublishTopic:      "reorg",
+		WithdrawalPublishTopic: "withdrawal",
+		Transport:              transport,
+		StreamMaxLen:           maxLen,
+	}
+}
+
+// bootstrap connects to every backing service the indexer needs and starts
+// the block processor queue, which stops once ctx is cancelled.
+func bootstrap(ctx context.Context, node *data.BlockChainNodeConn) (*app, error) {
+	latest, err := node.RPC.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest block number: %w", err)
+	}
+
+	policy, err := queue.PolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkPolicy(ctx, node.RPC, policy); err != nil {
+		return nil, err
+	}
+
+	logger.S().Infof("Confirming blocks by %s", policy)
+
+	_db := db.Connect()
+
+	pub, err := publisher.FromEnv(_db, newRedisInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up publishers: %w", err)
+	}
+
+	a := &app{
+		Node:      node,
+		Publisher: pub,
+		DB:        _db,
+		Queue:     queue.New(latest, policy),
+		Status: &data.StatusHolder{
+			State: &data.SyncState{
+				BlockCountAtStart:  db.GetBlockCount(_db),
+				MaxBlockNumAtStart: db.GetCurrentBlockNumber(_db),
+				LatestBlockNum:     latest,
+			},
+			Mutex: &sync.RWMutex{},
+		},
+	}
+
+	a.Status.SetStartedAt()
+
+	go a.Queue.Start(ctx)
+	a.Queue.Latest(latest)
+
+	return a, nil
+}
+
+// checkPolicy makes sure the node serves the block tag the confirmation
+// policy goes by, as blocks would otherwise never be confirmed.
+func checkPolicy(ctx context.Context, client *ethclient.Client, policy queue.ConfirmationPolicy) error {
+	var tag rpc.BlockNumber
+
+	switch policy.(type) {
+	case queue.SafeTag:
+		tag = rpc.SafeBlockNumber
+	case queue.FinalizedTag:
+		tag = rpc.FinalizedBlockNumber
+	default:
+		return nil
+	}
+
+	if _, err := client.HeaderByNumber(ctx, big.NewInt(tag.Int64())); err != nil {
+		return fmt.Errorf("node doesn't serve the %s block, which confirming blocks by %s needs: %w", tag.String(), policy, err)
+	}
+
+	return nil
+}
+
+func (a *app) Close() {
+	if a.Node.RPC != nil {
+		a.Node.RPC.Close()
+	}
+
+	if a.Node.WebSocket != nil {
+		a.Node.WebSocket.Close()
+	}
+
+	if err := a.Publisher.Close(); err != nil {
+		logger.S().Errorf("Failed to close publishers: %v", err.Error())
+	}
+
+	if sqlDB, err := a.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
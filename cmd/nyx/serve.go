@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/pubsub"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/internal/rest"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", envOr("HTTP_ADDR", ":7000"), "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	_db := db.Connect()
+	redis := newRedisInfo()
+
+	defer func() {
+		if err := redis.Client.Close(); err != nil {
+			logger.S().Errorf("Failed to close Redis connection: %v", err.Error())
+		}
+
+		if sqlDB, err := _db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	abis, err := registry.Load(_db)
+	if err != nil {
+		return err
+	}
+
+	if redis.IsStreams() {
+		go pubsub.ExpireGroups(ctx, redis.Client, time.Duration(10)*time.Minute)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           rest.NewServer(_db, redis, abis).Handler(),
+		ReadHeaderTimeout: time.Duration(10) * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+
+	go func() {
+		logger.S().Infof("Listening on %s", *addr)
+		errChan <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+
+	case <-ctx.Done():
+	}
+
+	logger.S().Info("Shutting down HTTP server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(10)*time.Second)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+func envOr(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
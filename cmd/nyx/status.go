@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/client"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+)
+
+func runStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	node := client.RPC()
+	defer node.Close()
+
+	head, err := node.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch latest block number: %w", err)
+	}
+
+	_db := db.Connect()
+	if sqlDB, err := _db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	count := db.GetBlockCount(_db)
+	latest := db.GetCurrentBlockNumber(_db)
+	oldest := db.GetCurrentOldestBlockNumber(_db)
+
+	var missing uint64
+	if count > 0 && latest-oldest+1 > count {
+		missing = latest - oldest + 1 - count
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Chain head\t%d\n", head)
+	fmt.Fprintf(w, "Latest indexed\t%d\n", latest)
+	fmt.Fprintf(w, "Oldest indexed\t%d\n", oldest)
+	fmt.Fprintf(w, "Blocks in DB\t%d\n", count)
+	fmt.Fprintf(w, "Missing in range\t%d\n", missing)
+
+	if head > latest {
+		fmt.Fprintf(w, "Behind head\t%d\n", head-latest)
+	}
+
+	if checkpoint, err := db.GetCheckpoint(_db, "backfill"); err == nil && checkpoint != nil {
+		fmt.Fprintf(w, "Backfill checkpoint\t%d (range %d-%d, saved %s)\n", checkpoint.Next, checkpoint.From, checkpoint.To, checkpoint.UpdatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
@@ -1,13 +1,269 @@
 package block
 
 import (
+	"context"
+	"fmt"
+	"math/big"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/internal/token"
 	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 	"gorm.io/gorm"
 )
 
-func ProcessBlock(client *ethclient.Client, block *types.Block, db *gorm.DB, redis )
\ No newline at end of file
+// FetchBlockByNumber pulls a block from the node and hands it over to
+// ProcessBlock. confirmed tells which queue phase the attempt belongs to.
+// Blocks which are confirmation deep already, e.g. while backfilling, are
+// published as confirmed by their first pass, without being fetched again.
+func FetchBlockByNumber(client *ethclient.Client, number uint64, _db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue, status *data.StatusHolder, confirmed bool) bool {
+	startingAt := time.Now().UTC()
+
+	deep := confirmed || queue.CanPublish(number, data.FinalityConfirmed)
+
+	block, err := client.BlockByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		logger.S().Errorf("Failed to fetch block %d: %s", number, err.Error())
+		failed(queue, number, confirmed)
+		return false
+	}
+
+	return ProcessBlock(client, block, _db, publisher, queue, status, confirmed, deep, startingAt)
+}
+
+func ProcessBlock(client *ethclient.Client, block *types.Block, _db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue, status *data.StatusHolder, confirmed bool, deep bool, startingAt time.Time) bool {
+	log := logger.S()
+
+	packed, err := BuildPackedBlock(client, block)
+	if err != nil {
+		log.Errorf("Failed to process block %d: %s", block.NumberU64(), err.Error())
+		failed(queue, block.NumberU64(), confirmed)
+		return false
+	}
+
+	chainLock.Lock()
+
+	if _, err := HandleReorg(client, block, _db, publisher, queue); err != nil {
+		chainLock.Unlock()
+		log.Errorf("Failed to check block %d for reorganization: %s", block.NumberU64(), err.Error())
+		failed(queue, block.NumberU64(), confirmed)
+		return false
+	}
+
+	inserted, err := db.StoreBlock(_db, packed)
+	chainLock.Unlock()
+	if err != nil {
+		log.Errorf("Failed to store block %d: %s", block.NumberU64(), err.Error())
+		failed(queue, block.NumberU64(), confirmed)
+		return false
+	}
+
+	if inserted {
+		status.IncrementBlocksInserted()
+		queue.Inserted(block.NumberU64())
+
+		if err := token.ResolveMetadata(client, _db, packed.TokenTransfers); err != nil {
+			log.Errorf("Failed to resolve token metadata of block %d: %s", block.NumberU64(), err.Error())
+		}
+	}
+
+	// Blocks deep enough, e.g. while backfilling, complete several phases at
+	// once, and are published with every finality they've reached. Only
+	// blocks which were confirmation deep when fetched are published as
+	// confirmed, as others may have been replaced since.
+	for _, finality := range data.Finalities {
+		if finality == data.FinalityConfirmed && !deep {
+			continue
+		}
+
+		if !queue.CanPublish(block.NumberU64(), finality) {
+			continue
+		}
+
+		if err := PublishBlock(publisher, packed, finality, queue.Confirmations(block.NumberU64())); err != nil {
+			log.Errorf("Failed to publish block %d as %s: %s", block.NumberU64(), finality, err.Error())
+			failed(queue, block.NumberU64(), confirmed)
+			return false
+		}
+
+		queue.Published(block.NumberU64(), finality)
+	}
+
+	if confirmed {
+		queue.ConfirmedDone(block.NumberU64())
+	} else {
+		queue.UnconfirmedDone(block.NumberU64())
+	}
+
+	status.IncrementBlocksProcessed()
+
+	log.Infof("Processed block %d [ %d txs, %d events ] in %s", block.NumberU64(), len(packed.Transactions), len(packed.Events), time.Since(startingAt))
+	return true
+}
+
+// BuildPackedBlock converts a chain block into the entities we persist,
+// fetching every receipt so that transaction state and logs are known.
+func BuildPackedBlock(client *ethclient.Client, block *types.Block) (*data.PackedBlock, error) {
+	receipts, err := fetchReceipts(client, block)
+	if err != nil {
+		return nil, err
+	}
+
+	packed := &data.PackedBlock{
+		Block:        BuildBlock(block),
+		Transactions: make([]*data.Transaction, 0, len(block.Transactions())),
+		Receipts:     make([]*data.Receipt, 0, len(block.Transactions())),
+		Events:       make([]*data.Event, 0),
+		Withdrawals:  BuildWithdrawals(block),
+	}
+
+	for i, tx := range block.Transactions() {
+		receipt := receipts[i]
+
+		if receipt.TxHash != tx.Hash() {
+			return nil, fmt.Errorf("receipt %s does not belong to transaction %s", receipt.TxHash.Hex(), tx.Hash().Hex())
+		}
+
+		transaction, err := BuildTransaction(block, tx, receipt)
+		if err != nil {
+			return nil, err
+		}
+
+		packed.Transactions = append(packed.Transactions, transaction)
+		packed.Receipts = append(packed.Receipts, transaction.Receipt)
+		packed.Events = append(packed.Events, BuildEvents(block, receipt)...)
+	}
+
+	packed.TokenTransfers = token.BuildTokenTransfers(packed.Events)
+	packed.TokenApprovals = token.BuildTokenApprovals(packed.Events)
+	packed.NFTTransfers = token.BuildNFTTransfers(packed.Events)
+
+	if IsTracingEnabled() {
+		packed.InternalTransactions, err = TraceBlock(client, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return packed, nil
+}
+
+func BuildBlock(block *types.Block) *data.Block {
+	packed := &data.Block{
+		Hash:                block.Hash().Hex(),
+		Number:              block.NumberU64(),
+		Time:                block.Time(),
+		ParentHash:          block.ParentHash().Hex(),
+		Difficulty:          block.Difficulty().String(),
+		GasUsed:             block.GasUsed(),
+		GasLimit:            block.GasLimit(),
+		Nonce:               fmt.Sprintf("%d", block.Nonce()),
+		Miner:               block.Coinbase().Hex(),
+		Size:                float64(block.Size()),
+		StateRootHash:       block.Root().Hex(),
+		UncleHash:           block.UncleHash().Hex(),
+		TransactionRootHash: block.TxHash().Hex(),
+		ReceiptRootHash:     block.ReceiptHash().Hex(),
+		ExtraData:           block.Extra(),
+	}
+
+	// Fields below were introduced by London, Shanghai and Cancun, so
+	// they're missing from older blocks.
+	if fee := block.BaseFee(); fee != nil {
+		packed.BaseFee = fee.String()
+	}
+
+	if gas := block.BlobGasUsed(); gas != nil {
+		packed.BlobGasUsed = *gas
+	}
+
+	if gas := block.ExcessBlobGas(); gas != nil {
+		packed.ExcessBlobGas = *gas
+	}
+
+	if root := block.Header().WithdrawalsHash; root != nil {
+		packed.WithdrawalsRoot = root.Hex()
+	}
+
+	if root := block.BeaconRoot(); root != nil {
+		packed.ParentBeaconRoot = root.Hex()
+	}
+
+	return packed
+}
+
+func BuildWithdrawals(block *types.Block) []*data.Withdrawal {
+	withdrawals := make([]*data.Withdrawal, 0, len(block.Withdrawals()))
+
+	for _, w := range block.Withdrawals() {
+		withdrawals = append(withdrawals, &data.Withdrawal{
+			Index:          w.Index,
+			ValidatorIndex: w.Validator,
+			Address:        w.Address.Hex(),
+			Amount:         w.Amount,
+			BlockHash:      block.Hash().Hex(),
+			BlockNumber:    block.NumberU64(),
+			Timestamp:      block.Time(),
+		})
+	}
+
+	return withdrawals
+}
+
+// fetchReceipts prefers a single eth_getBlockReceipts call and falls back to
+// fetching receipts one by one for nodes which don't support it.
+func fetchReceipts(client *ethclient.Client, block *types.Block) ([]*types.Receipt, error) {
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	receipts, err := client.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+	if err == nil && len(receipts) == len(txs) {
+		return receipts, nil
+	}
+
+	receipts = make([]*types.Receipt, len(txs))
+	errs := make([]error, len(txs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for i, tx := range txs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, tx *types.Transaction) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			receipts[i], errs[i] = client.TransactionReceipt(context.Background(), tx.Hash())
+		}(i, tx)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch receipt of %s: %w", txs[i].Hash().Hex(), err)
+		}
+	}
+
+	return receipts, nil
+}
+
+func failed(queue *q.BlockProcessorQueue, number uint64, confirmed bool) {
+	if confirmed {
+		queue.ConfirmedFailed(number)
+		return
+	}
+
+	queue.UnconfirmedFailed(number)
+}
@@ -0,0 +1,89 @@
+package block
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// FollowFinality keeps the queue up to date with the safe and finalized
+// heads of the chain, polling the RPC endpoint until ctx is cancelled. Blocks
+// are published as safe or finalized by them, and confirmed by them under
+// the tag confirmation policies. Nodes which don't know of either, e.g.
+// pre-merge chains, never have anything published as safe or finalized.
+func FollowFinality(ctx context.Context, client *ethclient.Client, queue *q.BlockProcessorQueue) {
+	log := logger.S()
+
+	ticker := time.NewTicker(getPollInterval())
+	defer ticker.Stop()
+
+	warned := false
+
+	for {
+		safe, err := headNumber(ctx, client, rpc.SafeBlockNumber)
+		if err == nil {
+			var finalized uint64
+
+			finalized, err = headNumber(ctx, client, rpc.FinalizedBlockNumber)
+			if err == nil {
+				queue.Finality(safe, finalized)
+				warned = false
+			}
+		}
+
+		if err != nil && !warned && ctx.Err() == nil {
+			log.Warnf("Failed to fetch safe and finalized blocks: %s", err.Error())
+			warned = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func headNumber(ctx context.Context, client *ethclient.Client, tag rpc.BlockNumber) (uint64, error) {
+	header, err := client.HeaderByNumber(ctx, big.NewInt(tag.Int64()))
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// publishStoredBlock publishes an already processed block with a finality
+// it has reached since, reading it back from the database.
+func publishStoredBlock(_db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue, number uint64, finality string) {
+	log := logger.S()
+
+	packed, err := db.GetPackedBlock(_db, number)
+	if err != nil {
+		log.Errorf("Failed to read block %d for publishing as %s: %s", number, finality, err.Error())
+		queue.FinalityFailed(number)
+		return
+	}
+
+	if packed == nil {
+		log.Errorf("Block %d isn't stored, can't publish it as %s", number, finality)
+		queue.FinalityFailed(number)
+		return
+	}
+
+	if err := PublishBlock(publisher, packed, finality, queue.Confirmations(number)); err != nil {
+		log.Errorf("Failed to publish block %d as %s: %s", number, finality, err.Error())
+		queue.FinalityFailed(number)
+		return
+	}
+
+	queue.Published(number, finality)
+}
@@ -0,0 +1,173 @@
+package block
+
+import (
+	"context"
+	"math"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+const maxResubscribeDelay = time.Duration(60) * time.Second
+
+type headFollower struct {
+	node   *data.BlockChainNodeConn
+	queue  *q.BlockProcessorQueue
+	status *data.StatusHolder
+	last   uint64
+}
+
+// FollowHead keeps indexing new blocks as they're mined, starting from the
+// block the queue was created with. New heads are received over the
+// WebSocket connection; whenever that subscription drops, it polls the RPC
+// endpoint instead while trying to resubscribe with an increasing delay.
+func FollowHead(ctx context.Context, node *data.BlockChainNodeConn, queue *q.BlockProcessorQueue, status *data.StatusHolder) {
+	log := logger.S()
+
+	f := &headFollower{
+		node:   node,
+		queue:  queue,
+		status: status,
+	}
+
+	if queue.StartedWith > 0 {
+		f.last = queue.StartedWith - 1
+		f.onHead(queue.StartedWith)
+	}
+
+	if node.WebSocket == nil {
+		log.Warn("No WebSocket endpoint configured, polling for new blocks")
+		f.poll(ctx, nil)
+		return
+	}
+
+	delay := time.Duration(1) * time.Second
+
+	for {
+		subscribedAt := time.Now()
+
+		err := f.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warnf("New head subscription dropped, falling back to polling: %v", err)
+
+		if time.Since(subscribedAt) > maxResubscribeDelay {
+			delay = time.Duration(1) * time.Second
+		}
+
+		resubscribe := time.NewTimer(delay)
+		f.poll(ctx, resubscribe.C)
+		resubscribe.Stop()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		delay = nextResubscribeDelay(delay)
+	}
+}
+
+// subscribe blocks while it receives new heads over WebSocket, returning
+// once the subscription fails or ctx is cancelled.
+func (f *headFollower) subscribe(ctx context.Context) error {
+	headers := make(chan *types.Header, 16)
+
+	sub, err := f.node.WebSocket.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	logger.S().Info("Subscribed to new heads")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err := <-sub.Err():
+			return err
+
+		case header := <-headers:
+			if number := header.Number.Uint64(); number <= f.last {
+				// A head at a height we've already seen replaces the block
+				// we have, so process it again for the reorg to be noticed.
+				f.queue.Reset(number)
+				continue
+			}
+
+			f.onHead(header.Number.Uint64())
+		}
+	}
+}
+
+// poll asks the RPC endpoint for the latest block number on an interval,
+// until ctx is cancelled or stop fires.
+func (f *headFollower) poll(ctx context.Context, stop <-chan time.Time) {
+	ticker := time.NewTicker(getPollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			number, err := f.node.RPC.BlockNumber(ctx)
+			if err != nil {
+				logger.S().Errorf("Failed to fetch latest block number: %s", err.Error())
+				continue
+			}
+
+			f.onHead(number)
+		}
+	}
+}
+
+// onHead queues every block up to the new head, filling in heights which were
+// skipped since the last one we've seen. They're processed by ProcessQueue,
+// so that catching up after an outage doesn't fetch thousands of blocks at
+// once.
+func (f *headFollower) onHead(number uint64) {
+	if number <= f.last {
+		return
+	}
+
+	f.queue.Latest(number)
+	f.status.SetLatestBlockNum(number)
+
+	for n := f.last + 1; n <= number; n++ {
+		f.queue.Schedule(n)
+	}
+
+	f.last = number
+}
+
+func nextResubscribeDelay(delay time.Duration) time.Duration {
+	next := time.Duration(math.Round(delay.Seconds()*(1.0+math.Sqrt(5.0))/2)) * time.Second
+	if next > maxResubscribeDelay {
+		return maxResubscribeDelay
+	}
+
+	return next
+}
+
+func getPollInterval() time.Duration {
+	if v := os.Getenv("POLL_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err == nil && interval > 0 {
+			return interval
+		}
+	}
+
+	return time.Duration(3) * time.Second
+}
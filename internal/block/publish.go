@@ -0,0 +1,83 @@
+package block
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// PublishBlock announces a block which has reached the given finality,
+// followed by all of its transactions, events and withdrawals, on their
+// respective topics.
+func PublishBlock(publisher data.Publisher, packed *data.PackedBlock, finality string, confirmations uint64) error {
+	messages := make([]*data.Message, 0, 1+len(packed.Transactions)+len(packed.Events)+len(packed.Withdrawals))
+
+	messages = append(messages, &data.Message{
+		Topic:       data.BlockTopic,
+		ID:          packed.Block.Hash,
+		BlockNumber: packed.Block.Number,
+		Data:        packed.Block,
+	})
+
+	for _, tx := range packed.Transactions {
+		contract := tx.To
+		if contract == "" {
+			contract = tx.ContractAddress
+		}
+
+		messages = append(messages, &data.Message{
+			Topic:       data.TransactionTopic,
+			ID:          fmt.Sprintf("%s-%s", tx.BlockHash, tx.Hash),
+			BlockNumber: tx.BlockNumber,
+			Contract:    contract,
+			Data:        tx,
+		})
+	}
+
+	for _, event := range packed.Events {
+		messages = append(messages, &data.Message{
+			Topic:       data.EventTopic,
+			ID:          fmt.Sprintf("%s-%d", event.BlockHash, event.Index),
+			BlockNumber: event.BlockNumber,
+			Contract:    event.Origin,
+			Data:        event,
+		})
+	}
+
+	for _, withdrawal := range packed.Withdrawals {
+		messages = append(messages, &data.Message{
+			Topic:       data.WithdrawalTopic,
+			ID:          fmt.Sprintf("%s-%d", withdrawal.BlockHash, withdrawal.Index),
+			BlockNumber: withdrawal.BlockNumber,
+			Contract:    withdrawal.Address,
+			Data:        withdrawal,
+		})
+	}
+
+	// The same data is published once for every finality, which buses
+	// mustn't take for a duplicate
+	for _, msg := range messages {
+		if finality != data.FinalityLatest {
+			msg.ID += "-" + finality
+		}
+
+		msg.Finality = finality
+		msg.Confirmations = confirmations
+	}
+
+	return publisher.Publish(context.Background(), messages...)
+}
+
+// PublishReorg lets subscribers know blocks they've received were orphaned.
+// Only latest blocks are expected to be, so it's only published as such.
+func PublishReorg(publisher data.Publisher, reorg *data.Reorg) error {
+	return publisher.Publish(context.Background(), &data.Message{
+		Topic:         data.ReorgTopic,
+		ID:            fmt.Sprintf("reorg-%d-%d-%d", reorg.CommonAncestor, reorg.FromBlock, reorg.ToBlock),
+		BlockNumber:   reorg.FromBlock,
+		Finality:      data.FinalityLatest,
+		Confirmations: 1,
+		Data:          reorg,
+	})
+}
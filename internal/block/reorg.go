@@ -0,0 +1,146 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const defaultMaxReorgDepth = 128
+
+// chainLock serialises checking blocks for reorganizations with storing them,
+// so that no worker stores a block on top of the ones another worker is
+// rolling back.
+var chainLock sync.Mutex
+
+// HandleReorg makes sure block extends the chain we've stored so far. When it
+// doesn't, it walks back to the last block on which the stored chain and the
+// canonical chain agree, drops everything above it, puts the dropped heights
+// back into the queue and announces the reorganization. A nil result means
+// no reorganization happened.
+func HandleReorg(client *ethclient.Client, block *types.Block, _db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue) (*data.Reorg, error) {
+	number := block.NumberU64()
+
+	stored, err := db.GetBlockByNumber(_db, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored != nil && stored.Hash == block.Hash().Hex() {
+		return nil, nil
+	}
+
+	if number == 0 {
+		return nil, nil
+	}
+
+	parent, err := db.GetBlockByNumber(_db, number-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var ancestor uint64
+
+	if parent == nil || parent.Hash == block.ParentHash().Hex() {
+		if stored == nil {
+			return nil, nil
+		}
+
+		ancestor = number - 1
+	} else {
+		ancestor, err = findCommonAncestor(client, _db, number-1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	orphaned, err := db.DeleteBlocksAbove(_db, ancestor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orphaned) == 0 {
+		return nil, nil
+	}
+
+	reorg := &data.Reorg{
+		CommonAncestor: ancestor,
+		FromBlock:      orphaned[0].Number,
+		ToBlock:        orphaned[len(orphaned)-1].Number,
+		OrphanedBlocks: make([]string, 0, len(orphaned)),
+	}
+
+	for _, b := range orphaned {
+		reorg.OrphanedBlocks = append(reorg.OrphanedBlocks, b.Hash)
+
+		if b.Number != number {
+			queue.Reset(b.Number)
+		}
+	}
+
+	// Heights between the common ancestor and this block which we never had
+	// stored still need to be fetched from the new canonical chain.
+	for n := ancestor + 1; n < number; n++ {
+		queue.Reset(n)
+	}
+
+	logger.S().Warnf("Chain reorganization detected at block %d, rolled back to common ancestor %d [ %d blocks orphaned ]", number, ancestor, len(orphaned))
+
+	if err := PublishReorg(publisher, reorg); err != nil {
+		logger.S().Errorf("Failed to publish reorg notification: %s", err.Error())
+	}
+
+	return reorg, nil
+}
+
+// findCommonAncestor walks back from the given height until it finds a stored
+// block whose hash matches the canonical hash reported by the node.
+func findCommonAncestor(client *ethclient.Client, _db *gorm.DB, from uint64) (uint64, error) {
+	maxDepth := getMaxReorgDepth()
+
+	for n := from; ; n-- {
+		if from-n > maxDepth {
+			return 0, fmt.Errorf("no common ancestor found within %d blocks of %d", maxDepth, from)
+		}
+
+		stored, err := db.GetBlockByNumber(_db, n)
+		if err != nil {
+			return 0, err
+		}
+
+		if stored == nil {
+			return n, nil
+		}
+
+		header, err := client.HeaderByNumber(context.Background(), new(big.Int).SetUint64(n))
+		if err != nil {
+			return 0, err
+		}
+
+		if header.Hash().Hex() == stored.Hash || n == 0 {
+			return n, nil
+		}
+	}
+}
+
+func getMaxReorgDepth() uint64 {
+	if v := os.Getenv("MAX_REORG_DEPTH"); v != "" {
+		depth, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			return depth
+		}
+	}
+
+	return defaultMaxReorgDepth
+}
@@ -0,0 +1,71 @@
+package block
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"gorm.io/gorm"
+)
+
+// ProcessQueue keeps pulling blocks which are due for another attempt out of
+// the queue, either because an earlier attempt failed or because they have
+// collected enough confirmations, and processes them until ctx is cancelled.
+// Processed blocks which have since become safe or finalized are published
+// again with that finality.
+func ProcessQueue(ctx context.Context, client *ethclient.Client, _db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue, status *data.StatusHolder) {
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	run := func(number uint64, confirmed bool) {
+		sem <- struct{}{}
+
+		go func() {
+			defer func() { <-sem }()
+
+			FetchBlockByNumber(client, number, _db, publisher, queue, status, confirmed)
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		idle := true
+
+		if number, ok := queue.UnconfirmedNext(); ok {
+			run(number, false)
+			idle = false
+		}
+
+		if number, ok := queue.ConfirmedNext(); ok {
+			run(number, true)
+			idle = false
+		}
+
+		if number, finality, ok := queue.FinalityNext(); ok {
+			sem <- struct{}{}
+
+			go func() {
+				defer func() { <-sem }()
+
+				publishStoredBlock(_db, publisher, queue, number, finality)
+			}()
+
+			idle = false
+		}
+
+		if idle {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(100) * time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	q "github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	backfillCheckpoint = "backfill"
+	backfillBatchSize  = 1000
+)
+
+// SyncBlocksByRange fills every gap in [from, to] by feeding the missing
+// block numbers through a pool of workers, checkpointing its progress after
+// each batch. It returns once every block in the range is stored, or when
+// ctx is cancelled.
+func SyncBlocksByRange(ctx context.Context, client *ethclient.Client, _db *gorm.DB, publisher data.Publisher, queue *q.BlockProcessorQueue, status *data.StatusHolder, from uint64, to uint64, workers int, resume bool) error {
+	log := logger.S()
+
+	start := from
+
+	if resume {
+		checkpoint, err := db.GetCheckpoint(_db, backfillCheckpoint)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+
+		if checkpoint != nil && checkpoint.From == from && checkpoint.Next > from && checkpoint.Next <= to {
+			log.Infof("Resuming backfill from checkpoint at block %d", checkpoint.Next)
+			start = checkpoint.Next
+		}
+	}
+
+	stored, err := db.CountBlocksInRange(_db, start, to)
+	if err != nil {
+		return fmt.Errorf("failed to count stored blocks: %w", err)
+	}
+
+	total := to - start + 1 - stored
+	if total == 0 {
+		log.Infof("Blocks %d to %d are already synced", from, to)
+		return saveCheckpoint(_db, from, to, to+1)
+	}
+
+	log.Infof("Syncing %d missing blocks between %d and %d using %d workers", total, start, to, workers)
+
+	jobs, results, stop := startWorkers(workers, func(job *data.Job) bool {
+		return runJob(job, queue)
+	})
+	defer stop()
+
+	go reportProgress(ctx, status, total)
+
+	cursor := start
+
+	for {
+		if cursor > to {
+			// Blocks which failed were handed back to the queue for a retry,
+			// so look at the whole range again before calling it done.
+			cursor = start
+		}
+
+		missing, err := db.GetMissingBlockNumbers(_db, cursor, to, backfillBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to look up missing blocks: %w", err)
+		}
+
+		if len(missing) == 0 {
+			if cursor == start {
+				break
+			}
+
+			cursor = to + 1
+			continue
+		}
+
+		batch := make([]*data.Job, 0, len(missing))
+		for _, number := range missing {
+			batch = append(batch, &data.Job{
+				Client:    client,
+				DB:        _db,
+				Publisher: publisher,
+				Block:     number,
+				Status:    status,
+			})
+		}
+
+		result := dispatch(ctx, jobs, results, batch)
+
+		if err := checkpoint(_db, from, start, to); err != nil {
+			log.Errorf("Failed to save backfill checkpoint: %s", err.Error())
+		}
+
+		if ctx.Err() != nil {
+			log.Infof("Backfill interrupted, progress saved")
+			return nil
+		}
+
+		if result.Success == 0 && cursor == start {
+			// Nothing went through, give the queue some time to retry
+			// before scanning again.
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Duration(5) * time.Second):
+			}
+		}
+
+		cursor = missing[len(missing)-1] + 1
+	}
+
+	log.Infof("Synced blocks %d to %d in %s", from, to, status.ElapsedTime())
+	return saveCheckpoint(_db, from, to, to+1)
+}
+
+// startWorkers runs a pool of workers handling jobs with run, until stop is
+// called.
+func startWorkers(workers int, run func(*data.Job) bool) (chan<- *data.Job, <-chan bool, func()) {
+	jobs := make(chan *data.Job, workers)
+	results := make(chan bool, workers)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				results <- run(job)
+			}
+		}()
+	}
+
+	return jobs, results, func() {
+		close(jobs)
+		wg.Wait()
+	}
+}
+
+// dispatch hands a batch of jobs over to the workers, collecting results
+// while doing so, as workers can't take on more jobs until theirs are
+// received. Once ctx is cancelled, it stops dispatching and waits for the
+// jobs already handed over.
+func dispatch(ctx context.Context, jobs chan<- *data.Job, results <-chan bool, batch []*data.Job) data.ResultStatus {
+	var result data.ResultStatus
+
+	done := ctx.Done()
+	next, pending := 0, 0
+
+	for next < len(batch) || pending > 0 {
+		var send chan<- *data.Job
+		var job *data.Job
+
+		if next < len(batch) {
+			send = jobs
+			job = batch[next]
+		}
+
+		select {
+		case <-done:
+			done = nil
+			next = len(batch)
+
+		case send <- job:
+			next++
+			pending++
+
+		case ok := <-results:
+			pending--
+
+			if ok {
+				result.Success++
+			} else {
+				result.Failure++
+			}
+		}
+	}
+
+	return result
+}
+
+// runJob claims the job's block in the queue and processes it. Blocks already
+// claimed by someone else, e.g. the head follower, are left to them.
+func runJob(job *data.Job, queue *q.BlockProcessorQueue) bool {
+	if !queue.Put(job.Block) {
+		return false
+	}
+
+	return FetchBlockByNumber(job.Client, job.Block, job.DB, job.Publisher, queue, job.Status, false)
+}
+
+// checkpoint records the lowest block in the range which still isn't stored,
+// as everything below it is done.
+func checkpoint(_db *gorm.DB, from uint64, start uint64, to uint64) error {
+	missing, err := db.GetMissingBlockNumbers(_db, start, to, 1)
+	if err != nil {
+		return err
+	}
+
+	next := to + 1
+	if len(missing) != 0 {
+		next = missing[0]
+	}
+
+	return saveCheckpoint(_db, from, to, next)
+}
+
+func saveCheckpoint(_db *gorm.DB, from uint64, to uint64, next uint64) error {
+	return db.SaveCheckpoint(_db, &data.SyncCheckpoint{
+		Name:      backfillCheckpoint,
+		From:      from,
+		To:        to,
+		Next:      next,
+		UpdatedAt: time.Now().UTC(),
+	})
+}
+
+func reportProgress(ctx context.Context, status *data.StatusHolder, total uint64) {
+	ticker := time.NewTicker(time.Duration(10) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			done := status.Done()
+			elapsed := status.ElapsedTime()
+
+			if done == 0 || elapsed <= 0 {
+				continue
+			}
+
+			rate := float64(done) / elapsed.Seconds()
+
+			var eta time.Duration
+			if done < total {
+				eta = time.Duration(float64(total-done)/rate) * time.Second
+			}
+
+			logger.S().Infof("Backfill progress: %d/%d blocks, %.2f blocks/sec, ETA %s", done, total, rate, eta)
+
+			if done >= total {
+				return
+			}
+		}
+	}
+}
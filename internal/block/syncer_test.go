@@ -0,0 +1,57 @@
+package block
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+func TestDispatchBatchLargerThanWorkers(t *testing.T) {
+	jobs, results, stop := startWorkers(4, func(job *data.Job) bool {
+		return job.Block%3 != 0
+	})
+	defer stop()
+
+	batch := make([]*data.Job, 0, 1000)
+	for i := uint64(0); i < 1000; i++ {
+		batch = append(batch, &data.Job{Block: i})
+	}
+
+	done := make(chan data.ResultStatus)
+	go func() {
+		done <- dispatch(context.Background(), jobs, results, batch)
+	}()
+
+	select {
+	case result := <-done:
+		if result.Success != 666 || result.Failure != 334 {
+			t.Fatalf("expected 666 successes and 334 failures, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispatch didn't return")
+	}
+}
+
+func TestDispatchStopsOnceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	jobs, results, stop := startWorkers(2, func(job *data.Job) bool {
+		if job.Block == 10 {
+			cancel()
+		}
+		return true
+	})
+	defer stop()
+
+	batch := make([]*data.Job, 0, 1000)
+	for i := uint64(0); i < 1000; i++ {
+		batch = append(batch, &data.Job{Block: i})
+	}
+
+	result := dispatch(ctx, jobs, results, batch)
+	if result.Total() >= 1000 {
+		t.Fatalf("expected dispatching to stop early, got %+v", result)
+	}
+}
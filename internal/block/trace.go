@@ -0,0 +1,125 @@
+package block
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// callFrame is a single call as reported by geth's callTracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *hexutil.Big    `json:"value"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output"`
+	Error   string          `json:"error"`
+	Calls   []*callFrame    `json:"calls"`
+}
+
+type txTraceResult struct {
+	TxHash common.Hash `json:"txHash"`
+	Result *callFrame  `json:"result"`
+	Error  string      `json:"error"`
+}
+
+// IsTracingEnabled tells whether internal transactions should be indexed.
+// It's opt-in, as it needs the node to expose the debug namespace.
+func IsTracingEnabled() bool {
+	return os.Getenv("TRACE_INTERNAL_TRANSACTIONS") == "true"
+}
+
+// TraceBlock runs every transaction of the block through the callTracer and
+// returns the calls made by contracts, leaving out the top-level calls which
+// are stored as transactions already. It's traced by hash, so that a reorg
+// can't get the traces of another block at the same height mixed up with it.
+func TraceBlock(client *ethclient.Client, block *types.Block) ([]*data.InternalTransaction, error) {
+	if len(block.Transactions()) == 0 {
+		return nil, nil
+	}
+
+	var results []*txTraceResult
+
+	err := client.Client().CallContext(context.Background(), &results, "debug_traceBlockByHash", block.Hash(), map[string]interface{}{
+		"tracer": "callTracer",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block: %w", err)
+	}
+
+	if len(results) != len(block.Transactions()) {
+		return nil, fmt.Errorf("got %d traces for %d transactions", len(results), len(block.Transactions()))
+	}
+
+	internal := make([]*data.InternalTransaction, 0)
+
+	for i, result := range results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("failed to trace transaction %s: %s", block.Transactions()[i].Hash().Hex(), result.Error)
+		}
+
+		if result.Result == nil {
+			continue
+		}
+
+		// Older nodes don't report the hash alongside each trace, but do keep
+		// them in the order of the block's transactions.
+		txHash := block.Transactions()[i].Hash()
+		if result.TxHash != (common.Hash{}) && result.TxHash != txHash {
+			return nil, fmt.Errorf("trace of %s doesn't belong to transaction %s", result.TxHash.Hex(), txHash.Hex())
+		}
+
+		for j, call := range result.Result.Calls {
+			internal = flattenCallFrame(internal, call, []int64{int64(j)}, txHash.Hex(), block)
+		}
+	}
+
+	return internal, nil
+}
+
+func flattenCallFrame(internal []*data.InternalTransaction, frame *callFrame, traceAddress []int64, txHash string, block *types.Block) []*data.InternalTransaction {
+	tx := &data.InternalTransaction{
+		TransactionHash: txHash,
+		TraceAddress:    traceAddress,
+		Type:            frame.Type,
+		From:            frame.From.Hex(),
+		Value:           "0",
+		Gas:             uint64(frame.Gas),
+		GasUsed:         uint64(frame.GasUsed),
+		Input:           frame.Input,
+		Output:          frame.Output,
+		Error:           frame.Error,
+		Depth:           uint(len(traceAddress)),
+		BlockHash:       block.Hash().Hex(),
+		BlockNumber:     block.NumberU64(),
+		Timestamp:       block.Time(),
+	}
+
+	if frame.To != nil {
+		tx.To = frame.To.Hex()
+	}
+
+	if frame.Value != nil {
+		tx.Value = frame.Value.ToInt().String()
+	}
+
+	internal = append(internal, tx)
+
+	for i, call := range frame.Calls {
+		child := make([]int64, len(traceAddress), len(traceAddress)+1)
+		copy(child, traceAddress)
+
+		internal = flattenCallFrame(internal, call, append(child, int64(i)), txHash, block)
+	}
+
+	return internal
+}
@@ -0,0 +1,118 @@
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+)
+
+func BuildTransaction(block *types.Block, tx *types.Transaction, receipt *types.Receipt) (*data.Transaction, error) {
+	sender, err := util.TransactionSender(block, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive sender of %s: %w", tx.Hash().Hex(), err)
+	}
+
+	transaction := &data.Transaction{
+		Hash:        tx.Hash().Hex(),
+		From:        sender.Hex(),
+		Value:       tx.Value().String(),
+		Data:        tx.Data(),
+		Gas:         tx.Gas(),
+		GasPrice:    tx.GasPrice().String(),
+		Cost:        util.CalcGasCost(tx.Gas(), tx.GasPrice()).String(),
+		Nonce:       tx.Nonce(),
+		Type:        tx.Type(),
+		State:       receipt.Status,
+		BlockHash:   block.Hash().Hex(),
+		BlockNumber: block.NumberU64(),
+		Timestamp:   block.Time(),
+	}
+
+	if tx.To() != nil {
+		transaction.To = tx.To().Hex()
+	} else {
+		transaction.ContractAddress = receipt.ContractAddress.Hex()
+	}
+
+	if tx.Type() >= types.AccessListTxType {
+		accessList, err := json.Marshal(tx.AccessList())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode access list of %s: %w", tx.Hash().Hex(), err)
+		}
+
+		transaction.AccessList = string(accessList)
+	}
+
+	if tx.Type() >= types.DynamicFeeTxType {
+		transaction.MaxFeePerGas = tx.GasFeeCap().String()
+		transaction.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	}
+
+	if tx.Type() == types.BlobTxType {
+		transaction.MaxFeePerBlobGas = tx.BlobGasFeeCap().String()
+		transaction.BlobVersionedHashes = c.StringifyEventTopics(tx.BlobHashes())
+	}
+
+	transaction.Receipt = BuildReceipt(block, tx, receipt)
+
+	return transaction, nil
+}
+
+// BuildReceipt captures the gas actually spent by a transaction, which lets
+// its real fee be computed rather than the upper bound kept in Cost.
+func BuildReceipt(block *types.Block, tx *types.Transaction, receipt *types.Receipt) *data.Receipt {
+	// Nodes predating London don't report an effective gas price, in which
+	// case it's the one the transaction was sent with.
+	gasPrice := receipt.EffectiveGasPrice
+	if gasPrice == nil {
+		gasPrice = tx.GasPrice()
+	}
+
+	fee := util.CalcGasCost(receipt.GasUsed, gasPrice)
+
+	blobGasPrice := ""
+	if receipt.BlobGasPrice != nil {
+		blobGasPrice = receipt.BlobGasPrice.String()
+		fee.Add(fee, util.CalcGasCost(receipt.BlobGasUsed, receipt.BlobGasPrice))
+	}
+
+	return &data.Receipt{
+		TransactionHash:   tx.Hash().Hex(),
+		TransactionIndex:  receipt.TransactionIndex,
+		Type:              receipt.Type,
+		Status:            receipt.Status,
+		CumulativeGasUsed: receipt.CumulativeGasUsed,
+		GasUsed:           receipt.GasUsed,
+		EffectiveGasPrice: gasPrice.String(),
+		Fee:               fee.String(),
+		BlobGasUsed:       receipt.BlobGasUsed,
+		BlobGasPrice:      blobGasPrice,
+		LogsBloom:         hexutil.Encode(receipt.Bloom.Bytes()),
+		BlockHash:         block.Hash().Hex(),
+		BlockNumber:       block.NumberU64(),
+	}
+}
+
+func BuildEvents(block *types.Block, receipt *types.Receipt) []*data.Event {
+	events := make([]*data.Event, 0, len(receipt.Logs))
+
+	for _, l := range receipt.Logs {
+		events = append(events, &data.Event{
+			Origin:          l.Address.Hex(),
+			Index:           l.Index,
+			Topics:          c.StringifyEventTopics(l.Topics),
+			Data:            l.Data,
+			TransactionHash: l.TxHash.Hex(),
+			BlockHash:       block.Hash().Hex(),
+			BlockNumber:     block.NumberU64(),
+			Timestamp:       block.Time(),
+		})
+	}
+
+	return events
+}
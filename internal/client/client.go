@@ -6,6 +6,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/go-redis/redis/v8"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
 	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 )
 
@@ -58,3 +59,25 @@ func getRedisClient() *redis.Client {
 	}
 	return client
 }
+
+// Connect dials the RPC endpoint and, when WS_URL is set, the WebSocket
+// endpoint too.
+func Connect() *data.BlockChainNodeConn {
+	conn := &data.BlockChainNodeConn{
+		RPC: getClient(true),
+	}
+
+	if os.Getenv("WS_URL") != "" {
+		conn.WebSocket = getClient(false)
+	}
+
+	return conn
+}
+
+func RPC() *ethclient.Client {
+	return getClient(true)
+}
+
+func Redis() *redis.Client {
+	return getRedisClient()
+}
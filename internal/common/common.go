@@ -2,9 +2,18 @@ package common
 
 import (
 	"errors"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	hashPattern      = regexp.MustCompile("^0x[0-9a-fA-F]{64}$")
+	addressPattern   = regexp.MustCompile("^0x[0-9a-fA-F]{40}$")
+	signaturePattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*\([a-zA-Z0-9,\[\]() ]*\)$`)
 )
 
 func StringifyEventTopics(data []common.Hash) []string {
@@ -58,3 +67,24 @@ func RangeChecker(from string, to string, limit uint64) (uint64, uint64, error)
 
 	return uint64(fromInt), uint64(toInt), nil
 }
+
+// NormalizeEventTopic turns the shorthands accepted in event filters into
+// the topic they stand for: an event signature such as
+// `Transfer(address,address,uint256)` in place of topic0 is hashed, and an
+// address in place of any other topic is left padded to 32 bytes. It
+// reports false for anything else but a topic hash.
+func NormalizeEventTopic(position int, topic string) (string, bool) {
+	switch {
+	case hashPattern.MatchString(topic):
+		return common.HexToHash(topic).Hex(), true
+
+	case position == 0 && signaturePattern.MatchString(topic):
+		signature := strings.ReplaceAll(topic, " ", "")
+		return crypto.Keccak256Hash([]byte(signature)).Hex(), true
+
+	case position != 0 && addressPattern.MatchString(topic):
+		return common.BytesToHash(common.HexToAddress(topic).Bytes()).Hex(), true
+	}
+
+	return "", false
+}
@@ -0,0 +1,37 @@
+package common
+
+import "testing"
+
+func TestNormalizeEventTopic(t *testing.T) {
+	const transfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+	tests := []struct {
+		position int
+		topic    string
+		want     string
+		ok       bool
+	}{
+		{0, "Transfer(address,address,uint256)", transfer, true},
+		{0, "Transfer(address, address, uint256)", transfer, true},
+		{0, transfer, transfer, true},
+		{0, "0xDDF252AD1BE2C89B69C2B068FC378DAA952BA7F163C4A11628F55A4DF523B3EF", transfer, true},
+		{1, "0x000000000000000000000000000000000000dEaD", "0x000000000000000000000000000000000000000000000000000000000000dead", true},
+		{1, transfer, transfer, true},
+
+		// Signatures only stand for topic0, and addresses for the others
+		{1, "Transfer(address,address,uint256)", "", false},
+		{0, "0x000000000000000000000000000000000000dEaD", "", false},
+
+		{0, "Transfer", "", false},
+		{0, "0xddf252ad", "", false},
+		{0, "0x" + "zz" + transfer[4:], "", false},
+		{2, "", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := NormalizeEventTopic(test.position, test.topic)
+		if ok != test.ok || got != test.want {
+			t.Errorf("NormalizeEventTopic(%d, %q) = %q, %t, expected %q, %t", test.position, test.topic, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 type Block struct {
 	Hash                string  `json:"hash" gorm:"column:hash;primaryKey"`
-	Number              uint64  `json:"number" gorm:"column:number"`
+	Number              uint64  `json:"number" gorm:"column:number;index"`
 	Time                uint64  `json:"time" gorm:"column:time"`
 	ParentHash          string  `json:"parent_hash" gorm:"column:parent_hash"`
 	Difficulty          string  `json:"difficulty" gorm:"column:difficulty"`
@@ -24,6 +24,11 @@ type Block struct {
 	TransactionRootHash string  `json:"transaction_root_hash" gorm:"column:transaction_root_hash"`
 	ReceiptRootHash     string  `json:"receipt_root_hash" gorm:"column:receipt_root_hash"`
 	ExtraData           []byte  `json:"extra_data" gorm:"column:extra_data"`
+	BaseFee             string  `json:"base_fee" gorm:"column:base_fee"`
+	BlobGasUsed         uint64  `json:"blob_gas_used" gorm:"column:blob_gas_used"`
+	ExcessBlobGas       uint64  `json:"excess_blob_gas" gorm:"column:excess_blob_gas"`
+	WithdrawalsRoot     string  `json:"withdrawals_root" gorm:"column:withdrawals_root"`
+	ParentBeaconRoot    string  `json:"parent_beacon_root" gorm:"column:parent_beacon_root"`
 }
 
 type Blocks struct {
@@ -44,7 +49,7 @@ func (b *Block) MarshalJSON() ([]byte, error) {
 		extraData = fmt.Sprintf("0x%s", h)
 	}
 
-	return []byte(fmt.Sprintf(`{"hash":%q,"number":%d,"time":%d,"parentHash":%q,"difficulty":%q,"gasUsed":%d,"gasLimit":%d,"nonce":%q,"miner":%q,"size":%f,"stateRootHash":%q,"uncleHash":%q,"txRootHash":%q,"receiptRootHash":%q,"extraData":%q}`,
+	return []byte(fmt.Sprintf(`{"hash":%q,"number":%d,"time":%d,"parentHash":%q,"difficulty":%q,"gasUsed":%d,"gasLimit":%d,"nonce":%q,"miner":%q,"size":%f,"stateRootHash":%q,"uncleHash":%q,"txRootHash":%q,"receiptRootHash":%q,"extraData":%q,"baseFee":%q,"blobGasUsed":%d,"excessBlobGas":%d,"withdrawalsRoot":%q,"parentBeaconRoot":%q}`,
 		b.Hash,
 		b.Number,
 		b.Time,
@@ -59,7 +64,12 @@ func (b *Block) MarshalJSON() ([]byte, error) {
 		b.UncleHash,
 		b.TransactionRootHash,
 		b.ReceiptRootHash,
-		extraData)), nil
+		extraData,
+		b.BaseFee,
+		b.BlobGasUsed,
+		b.ExcessBlobGas,
+		b.WithdrawalsRoot,
+		b.ParentBeaconRoot)), nil
 }
 
 func (b *Block) ToJSON() []byte {
@@ -0,0 +1,14 @@
+package data
+
+import "time"
+
+// SyncCheckpoint remembers how far a named syncer got, so that it can pick
+// up from there after a restart. Every block below Next is known to be
+// stored.
+type SyncCheckpoint struct {
+	Name      string    `json:"name" gorm:"column:name;primaryKey"`
+	From      uint64    `json:"from" gorm:"column:from_block"`
+	To        uint64    `json:"to" gorm:"column:to_block"`
+	Next      uint64    `json:"next" gorm:"column:next_block"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
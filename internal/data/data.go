@@ -23,9 +23,28 @@ type StatusHolder struct {
 	Mutex *sync.RWMutex
 }
 
+// Transports data can be published to subscribers over. Pub/Sub drops
+// whatever is published while nobody's listening, whereas Streams keeps the
+// latest entries around for consumer groups to read and acknowledge.
+const (
+	PubSubTransport  = "pubsub"
+	StreamsTransport = "streams"
+)
+
 type RedisInfo struct {
-	Client                                               *redis.Client
-	BlockPublishTopic, TxPublishTopic, EventPublishTopic string
+	Client                                                                                          *redis.Client
+	BlockPublishTopic, TxPublishTopic, EventPublishTopic, ReorgPublishTopic, WithdrawalPublishTopic string
+
+	// Transport is either PubSubTransport or StreamsTransport, and
+	// StreamMaxLen bounds the length of each topic's stream.
+	Transport    string
+	StreamMaxLen int64
+}
+
+// IsStreams tells whether topics are published to Redis Streams rather than
+// Pub/Sub channels.
+func (r *RedisInfo) IsStreams() bool {
+	return r.Transport == StreamsTransport
 }
 
 type ResultStatus struct {
@@ -34,11 +53,24 @@ type ResultStatus struct {
 }
 
 type Job struct {
-	Client *ethclient.Client
-	DB     *gorm.DB
-	Redis  *RedisInfo
-	Block  uint64
-	Status *StatusHolder
+	Client    *ethclient.Client
+	DB        *gorm.DB
+	Publisher Publisher
+	Block     uint64
+	Status    *StatusHolder
+}
+
+type PackedBlock struct {
+	Block        *Block
+	Transactions []*Transaction
+	Receipts     []*Receipt
+	Events       []*Event
+	Withdrawals  []*Withdrawal
+
+	InternalTransactions []*InternalTransaction
+	TokenTransfers       []*TokenTransfer
+	TokenApprovals       []*TokenApproval
+	NFTTransfers         []*NFTTransfer
 }
 
 type BlockChainNodeConn struct {
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// Decoded is an event or calldata decoded against a known contract ABI.
+type Decoded struct {
+	Name      string        `json:"name"`
+	Signature string        `json:"signature"`
+	Args      []*DecodedArg `json:"args"`
+}
+
+// DecodedArg is a named, typed argument. Value holds numbers as decimal
+// strings and byte arrays as hex, so that nothing is lost on the way to JS.
+type DecodedArg struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Indexed bool        `json:"indexed,omitempty"`
+	Value   interface{} `json:"value"`
+}
+
+// ContractABI is an uploaded ABI, attached to a contract when Address is
+// set. Those without one are only used for decoding by signature.
+type ContractABI struct {
+	ID        uint      `json:"id" gorm:"column:id;primaryKey"`
+	Address   string    `json:"address" gorm:"column:address;index"`
+	ABI       string    `json:"abi" gorm:"column:abi;type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+type ContractABIs struct {
+	ContractABIs []*ContractABI `json:"abis"`
+}
+
+// decodedField renders the optional decoded field of an event or transaction.
+func decodedField(d *Decoded) (string, error) {
+	if d == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+
+	return `,"decoded":` + string(data), nil
+}
+
+func (c *ContractABI) MarshalJSON() ([]byte, error) {
+	abi := c.ABI
+	if !json.Valid([]byte(abi)) {
+		abi = "null"
+	}
+
+	return []byte(fmt.Sprintf(`{"id":%d,"address":%q,"abi":%s}`,
+		c.ID,
+		c.Address,
+		abi)), nil
+}
+
+func (cs *ContractABIs) ToJSON() []byte {
+	data, err := json.Marshal(cs)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling contract ABIs to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (c *ContractABI) ToJSON() []byte {
+	data, err := json.Marshal(c)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling contract ABI to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
@@ -11,14 +11,16 @@ import (
 )
 
 type Event struct {
-	Origin          string         `gorm:"column:origin"`
+	Origin          string         `gorm:"column:origin;index"`
 	Index           uint           `gorm:"column:index"`
 	Topics          pq.StringArray `gorm:"column:topics;type:text[]"`
 	Data            []byte         `gorm:"column:data"`
 	TransactionHash string         `gorm:"column:transaction_hash"`
 	BlockHash       string         `gorm:"column:block_hash"`
-	BlockNumber     uint64         `gorm:"column:block_number"`
+	BlockNumber     uint64         `gorm:"column:block_number;index"`
 	Timestamp       uint64         `gorm:"column:timestamp"`
+
+	Decoded *Decoded `gorm:"-"`
 }
 
 type Events struct {
@@ -26,10 +28,10 @@ type Events struct {
 }
 
 func (e *Event) MarshalBinary() (data []byte, err error) {
-	return e.MashalJSON()
+	return e.MarshalJSON()
 }
 
-func (e *Event) MashalJSON() ([]byte, error) {
+func (e *Event) MarshalJSON() ([]byte, error) {
 	data := ""
 
 	if h := hex.EncodeToString(e.Data); h != "" && h != strings.Repeat("0", 64) {
@@ -38,7 +40,12 @@ func (e *Event) MashalJSON() ([]byte, error) {
 
 	topics := strings.Join(strings.Fields(fmt.Sprintf("%q", e.Topics)), ",")
 
-	return []byte(fmt.Sprintf(`{"origin":%q,"index":%d,"topics":%v,"data":%q,"txHash":%q,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+	decoded, err := decodedField(e.Decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`{"origin":%q,"index":%d,"topics":%v,"data":%q,"txHash":%q,"blockHash":%q,"blockNumber":%d,"timestamp":%d%s}`,
 		e.Origin,
 		e.Index,
 		topics,
@@ -46,7 +53,8 @@ func (e *Event) MashalJSON() ([]byte, error) {
 		e.TransactionHash,
 		e.BlockHash,
 		e.BlockNumber,
-		e.Timestamp)), nil
+		e.Timestamp,
+		decoded)), nil
 }
 
 func (e *Event) ToJSON() []byte {
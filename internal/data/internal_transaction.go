@@ -0,0 +1,94 @@
+package data
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"github.com/lib/pq"
+)
+
+// InternalTransaction is a call made by a contract while executing a
+// transaction, as reported by the node's call tracer. TraceAddress locates
+// the call in the transaction's call tree, e.g. [0 2] is the third call made
+// by the first call of the transaction.
+type InternalTransaction struct {
+	TransactionHash string        `json:"transaction_hash" gorm:"column:transaction_hash;primaryKey"`
+	TraceAddress    pq.Int64Array `json:"trace_address" gorm:"column:trace_address;type:bigint[];primaryKey"`
+	Type            string        `json:"type" gorm:"column:type"`
+	From            string        `json:"from" gorm:"column:from;index"`
+	To              string        `json:"to" gorm:"column:to;index"`
+	Value           string        `json:"value" gorm:"column:value"`
+	Gas             uint64        `json:"gas" gorm:"column:gas"`
+	GasUsed         uint64        `json:"gas_used" gorm:"column:gas_used"`
+	Input           []byte        `json:"input" gorm:"column:input"`
+	Output          []byte        `json:"output" gorm:"column:output"`
+	Error           string        `json:"error" gorm:"column:error"`
+	Depth           uint          `json:"depth" gorm:"column:depth"`
+	BlockHash       string        `json:"block_hash" gorm:"column:block_hash"`
+	BlockNumber     uint64        `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp       uint64        `json:"timestamp" gorm:"column:timestamp"`
+}
+
+type InternalTransactions struct {
+	InternalTransactions []*InternalTransaction `json:"internalTransactions"`
+}
+
+func (i *InternalTransaction) MarshalBinary() ([]byte, error) {
+	return json.Marshal(i)
+}
+
+func (i *InternalTransaction) MarshalJSON() ([]byte, error) {
+	input := ""
+	if h := hex.EncodeToString(i.Input); h != "" {
+		input = fmt.Sprintf("0x%s", h)
+	}
+
+	output := ""
+	if h := hex.EncodeToString(i.Output); h != "" {
+		output = fmt.Sprintf("0x%s", h)
+	}
+
+	traceAddress := strings.Join(strings.Fields(fmt.Sprint([]int64(i.TraceAddress))), ",")
+
+	return []byte(fmt.Sprintf(`{"txHash":%q,"traceAddress":%s,"type":%q,"from":%q,"to":%q,"value":%q,"gas":%d,"gasUsed":%d,"input":%q,"output":%q,"error":%q,"depth":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+		i.TransactionHash,
+		traceAddress,
+		i.Type,
+		i.From,
+		i.To,
+		i.Value,
+		i.Gas,
+		i.GasUsed,
+		input,
+		output,
+		i.Error,
+		i.Depth,
+		i.BlockHash,
+		i.BlockNumber,
+		i.Timestamp)), nil
+}
+
+func (i *InternalTransaction) ToJSON() []byte {
+	data, err := json.Marshal(i)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling internal transaction to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (is *InternalTransactions) ToJSON() []byte {
+	data, err := json.Marshal(is)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling internal transactions to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
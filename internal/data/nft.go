@@ -0,0 +1,104 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+const (
+	ERC721  = "erc721"
+	ERC1155 = "erc1155"
+)
+
+// NFTTransfer is a single token moved by an ERC-721 Transfer or an ERC-1155
+// TransferSingle/TransferBatch event. Batches are split into one row per
+// token, told apart by BatchIndex.
+type NFTTransfer struct {
+	TransactionHash string `json:"transaction_hash" gorm:"column:transaction_hash;index"`
+	LogIndex        uint   `json:"log_index" gorm:"column:log_index;primaryKey;autoIncrement:false"`
+	BatchIndex      uint   `json:"batch_index" gorm:"column:batch_index;primaryKey;autoIncrement:false"`
+	Standard        string `json:"standard" gorm:"column:standard"`
+	Contract        string `json:"contract" gorm:"column:contract;index:idx_nft_transfers_token"`
+	TokenID         string `json:"token_id" gorm:"column:token_id;type:numeric;index:idx_nft_transfers_token"`
+	Operator        string `json:"operator" gorm:"column:operator"`
+	From            string `json:"from" gorm:"column:from;index"`
+	To              string `json:"to" gorm:"column:to;index"`
+	Amount          string `json:"amount" gorm:"column:amount;type:numeric"`
+	BlockHash       string `json:"block_hash" gorm:"column:block_hash;primaryKey"`
+	BlockNumber     uint64 `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp       uint64 `json:"timestamp" gorm:"column:timestamp"`
+}
+
+// NFTOwner is the amount of a token currently held by an owner, which is
+// at most 1 for ERC-721 while ERC-1155 tokens can be spread across owners.
+type NFTOwner struct {
+	Contract    string `json:"contract" gorm:"column:contract;primaryKey"`
+	TokenID     string `json:"token_id" gorm:"column:token_id;type:numeric;primaryKey"`
+	Owner       string `json:"owner" gorm:"column:owner;primaryKey;index"`
+	Standard    string `json:"standard" gorm:"column:standard"`
+	Amount      string `json:"amount" gorm:"column:amount;type:numeric"`
+	BlockNumber uint64 `json:"block_number" gorm:"column:block_number"`
+}
+
+type NFTTransfers struct {
+	NFTTransfers []*NFTTransfer `json:"nftTransfers"`
+}
+
+type NFTOwners struct {
+	NFTOwners []*NFTOwner `json:"nftOwners"`
+}
+
+func (n *NFTTransfer) MarshalBinary() ([]byte, error) {
+	return json.Marshal(n)
+}
+
+func (n *NFTTransfer) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"txHash":%q,"logIndex":%d,"batchIndex":%d,"standard":%q,"contract":%q,"tokenId":%q,"operator":%q,"from":%q,"to":%q,"amount":%q,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+		n.TransactionHash,
+		n.LogIndex,
+		n.BatchIndex,
+		n.Standard,
+		n.Contract,
+		n.TokenID,
+		n.Operator,
+		n.From,
+		n.To,
+		n.Amount,
+		n.BlockHash,
+		n.BlockNumber,
+		n.Timestamp)), nil
+}
+
+func (ns *NFTTransfers) ToJSON() []byte {
+	data, err := json.Marshal(ns)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling nft transfers to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (n *NFTOwner) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"contract":%q,"tokenId":%q,"owner":%q,"standard":%q,"amount":%q,"blockNumber":%d}`,
+		n.Contract,
+		n.TokenID,
+		n.Owner,
+		n.Standard,
+		n.Amount,
+		n.BlockNumber)), nil
+}
+
+func (ns *NFTOwners) ToJSON() []byte {
+	data, err := json.Marshal(ns)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling nft owners to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
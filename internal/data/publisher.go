@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"encoding"
+)
+
+// Topics indexed data is published on, before any naming a publisher applies.
+const (
+	BlockTopic       = "block"
+	TransactionTopic = "transaction"
+	EventTopic       = "event"
+	WithdrawalTopic  = "withdrawal"
+	ReorgTopic       = "reorg"
+)
+
+// Publisher sends indexed data to a message bus, such as Redis, Kafka or
+// NATS.
+type Publisher interface {
+	Publish(ctx context.Context, messages ...*Message) error
+	Close() error
+}
+
+// Message is a piece of indexed data on its way to a topic. ID stays the same
+// whenever the same data is published again, so that buses can drop the
+// duplicate, while BlockNumber and Contract are what it can be partitioned
+// by. Finality tells which phase its block has completed, and Confirmations
+// how many blocks the chain had from it up to the head, counting its own.
+type Message struct {
+	Topic         string
+	ID            string
+	BlockNumber   uint64
+	Contract      string
+	Finality      string
+	Confirmations uint64
+	Data          encoding.BinaryMarshaler
+}
+
+// Finalities subscribers can ask for: latest as soon as a block's indexed,
+// confirmed once the confirmation policy says so, and safe and finalized as
+// reported by the node.
+const (
+	FinalityLatest    = "latest"
+	FinalityConfirmed = "confirmed"
+	FinalitySafe      = "safe"
+	FinalityFinalized = "finalized"
+)
+
+var Finalities = []string{FinalityLatest, FinalityConfirmed, FinalitySafe, FinalityFinalized}
+
+func IsFinality(finality string) bool {
+	for _, f := range Finalities {
+		if f == finality {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FinalityTopic names the topic data of the given finality is published on.
+// Latest data keeps the bare topic name.
+func FinalityTopic(topic string, finality string) string {
+	if finality == "" || finality == FinalityLatest {
+		return topic
+	}
+
+	return topic + "." + finality
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+type Receipt struct {
+	TransactionHash   string `json:"transaction_hash" gorm:"column:transaction_hash;primaryKey"`
+	TransactionIndex  uint   `json:"transaction_index" gorm:"column:transaction_index"`
+	Type              uint8  `json:"type" gorm:"column:type"`
+	Status            uint64 `json:"status" gorm:"column:status"`
+	CumulativeGasUsed uint64 `json:"cumulative_gas_used" gorm:"column:cumulative_gas_used"`
+	GasUsed           uint64 `json:"gas_used" gorm:"column:gas_used"`
+	EffectiveGasPrice string `json:"effective_gas_price" gorm:"column:effective_gas_price"`
+	Fee               string `json:"fee" gorm:"column:fee"`
+	BlobGasUsed       uint64 `json:"blob_gas_used" gorm:"column:blob_gas_used"`
+	BlobGasPrice      string `json:"blob_gas_price" gorm:"column:blob_gas_price"`
+	LogsBloom         string `json:"logs_bloom" gorm:"column:logs_bloom"`
+	BlockHash         string `json:"block_hash" gorm:"column:block_hash"`
+	BlockNumber       uint64 `json:"block_number" gorm:"column:block_number;index"`
+}
+
+type Receipts struct {
+	Receipts []*Receipt `json:"receipts"`
+}
+
+func (r *Receipt) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *Receipt) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"txHash":%q,"txIndex":%d,"type":%d,"status":%d,"cumulativeGasUsed":%d,"gasUsed":%d,"effectiveGasPrice":%q,"fee":%q,"blobGasUsed":%d,"blobGasPrice":%q,"logsBloom":%q,"blockHash":%q,"blockNumber":%d}`,
+		r.TransactionHash,
+		r.TransactionIndex,
+		r.Type,
+		r.Status,
+		r.CumulativeGasUsed,
+		r.GasUsed,
+		r.EffectiveGasPrice,
+		r.Fee,
+		r.BlobGasUsed,
+		r.BlobGasPrice,
+		r.LogsBloom,
+		r.BlockHash,
+		r.BlockNumber)), nil
+}
+
+func (r *Receipt) ToJSON() []byte {
+	data, err := json.Marshal(r)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling receipt to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (rs *Receipts) ToJSON() []byte {
+	data, err := json.Marshal(rs)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling receipts to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
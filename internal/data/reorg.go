@@ -0,0 +1,30 @@
+package data
+
+import (
+	"encoding/json"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// Reorg describes a chain reorganization, listing every block which was
+// dropped from the canonical chain above the common ancestor.
+type Reorg struct {
+	CommonAncestor uint64   `json:"commonAncestor"`
+	FromBlock      uint64   `json:"fromBlock"`
+	ToBlock        uint64   `json:"toBlock"`
+	OrphanedBlocks []string `json:"orphanedBlocks"`
+}
+
+func (r *Reorg) MarshalBinary() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *Reorg) ToJSON() []byte {
+	data, err := json.Marshal(r)
+	if err != nil {
+		logger.S().Errorf("failed to marshal reorg to JSON: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
@@ -0,0 +1,212 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// Token is the metadata of an ERC-20 contract, as read from the contract
+// itself the first time one of its transfers was seen.
+type Token struct {
+	Address  string `json:"address" gorm:"column:address;primaryKey"`
+	Name     string `json:"name" gorm:"column:name"`
+	Symbol   string `json:"symbol" gorm:"column:symbol"`
+	Decimals uint8  `json:"decimals" gorm:"column:decimals"`
+}
+
+// TokenTransfer is a decoded ERC-20 Transfer event. Value is the raw amount,
+// which is scaled by the token's decimals when its metadata is known.
+type TokenTransfer struct {
+	TransactionHash string `json:"transaction_hash" gorm:"column:transaction_hash;index"`
+	LogIndex        uint   `json:"log_index" gorm:"column:log_index;primaryKey;autoIncrement:false"`
+	Token           string `json:"token" gorm:"column:token;index"`
+	From            string `json:"from" gorm:"column:from;index"`
+	To              string `json:"to" gorm:"column:to;index"`
+	Value           string `json:"value" gorm:"column:value;type:numeric"`
+	BlockHash       string `json:"block_hash" gorm:"column:block_hash;primaryKey"`
+	BlockNumber     uint64 `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp       uint64 `json:"timestamp" gorm:"column:timestamp"`
+
+	Metadata *Token `json:"metadata,omitempty" gorm:"-"`
+}
+
+// TokenApproval is a decoded ERC-20 Approval event.
+type TokenApproval struct {
+	TransactionHash string `json:"transaction_hash" gorm:"column:transaction_hash;index"`
+	LogIndex        uint   `json:"log_index" gorm:"column:log_index;primaryKey;autoIncrement:false"`
+	Token           string `json:"token" gorm:"column:token;index"`
+	Owner           string `json:"owner" gorm:"column:owner;index"`
+	Spender         string `json:"spender" gorm:"column:spender;index"`
+	Value           string `json:"value" gorm:"column:value;type:numeric"`
+	BlockHash       string `json:"block_hash" gorm:"column:block_hash;primaryKey"`
+	BlockNumber     uint64 `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp       uint64 `json:"timestamp" gorm:"column:timestamp"`
+
+	Metadata *Token `json:"metadata,omitempty" gorm:"-"`
+}
+
+// TokenBalance is the running balance of a holder, kept up to date as
+// transfers are indexed and reverted when their block is orphaned.
+type TokenBalance struct {
+	Token       string `json:"token" gorm:"column:token;primaryKey"`
+	Holder      string `json:"holder" gorm:"column:holder;primaryKey;index"`
+	Balance     string `json:"balance" gorm:"column:balance;type:numeric"`
+	BlockNumber uint64 `json:"block_number" gorm:"column:block_number"`
+
+	Metadata *Token `json:"metadata,omitempty" gorm:"-"`
+}
+
+type Tokens struct {
+	Tokens []*Token `json:"tokens"`
+}
+
+type TokenTransfers struct {
+	TokenTransfers []*TokenTransfer `json:"tokenTransfers"`
+}
+
+type TokenApprovals struct {
+	TokenApprovals []*TokenApproval `json:"tokenApprovals"`
+}
+
+type TokenBalances struct {
+	TokenBalances []*TokenBalance `json:"tokenBalances"`
+}
+
+// amount renders the fields describing a token amount, with its decimal form
+// only present once the token's metadata is known.
+func amount(key string, scaledKey string, value string, metadata *Token) string {
+	if metadata == nil {
+		return fmt.Sprintf(`%q:%q`, key, value)
+	}
+
+	return fmt.Sprintf(`%q:%q,%q:%q,"symbol":%q,"decimals":%d`,
+		key,
+		value,
+		scaledKey,
+		util.ToDecimal(value, int(metadata.Decimals)).String(),
+		metadata.Symbol,
+		metadata.Decimals)
+}
+
+func (t *Token) MarshalBinary() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *Token) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"address":%q,"name":%q,"symbol":%q,"decimals":%d}`,
+		t.Address,
+		t.Name,
+		t.Symbol,
+		t.Decimals)), nil
+}
+
+func (t *Token) ToJSON() []byte {
+	data, err := json.Marshal(t)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling token to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (ts *Tokens) ToJSON() []byte {
+	data, err := json.Marshal(ts)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling tokens to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (t *TokenTransfer) MarshalBinary() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *TokenTransfer) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"txHash":%q,"logIndex":%d,"token":%q,"from":%q,"to":%q,%s,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+		t.TransactionHash,
+		t.LogIndex,
+		t.Token,
+		t.From,
+		t.To,
+		amount("value", "scaledValue", t.Value, t.Metadata),
+		t.BlockHash,
+		t.BlockNumber,
+		t.Timestamp)), nil
+}
+
+func (t *TokenTransfer) ToJSON() []byte {
+	data, err := json.Marshal(t)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling token transfer to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (ts *TokenTransfers) ToJSON() []byte {
+	data, err := json.Marshal(ts)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling token transfers to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (t *TokenApproval) MarshalBinary() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *TokenApproval) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"txHash":%q,"logIndex":%d,"token":%q,"owner":%q,"spender":%q,%s,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+		t.TransactionHash,
+		t.LogIndex,
+		t.Token,
+		t.Owner,
+		t.Spender,
+		amount("value", "scaledValue", t.Value, t.Metadata),
+		t.BlockHash,
+		t.BlockNumber,
+		t.Timestamp)), nil
+}
+
+func (ts *TokenApprovals) ToJSON() []byte {
+	data, err := json.Marshal(ts)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling token approvals to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (b *TokenBalance) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"token":%q,"holder":%q,%s,"blockNumber":%d}`,
+		b.Token,
+		b.Holder,
+		amount("balance", "scaledBalance", b.Balance, b.Metadata),
+		b.BlockNumber)), nil
+}
+
+func (bs *TokenBalances) ToJSON() []byte {
+	data, err := json.Marshal(bs)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling token balances to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
@@ -7,23 +7,32 @@ import (
 	"strings"
 
 	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"github.com/lib/pq"
 )
 
 type Transaction struct {
-	Hash            string `json:"hash" gorm:"primaryKey;column:hash"`
-	From            string `json:"from" gorm:"column:from"`
-	To              string `json:"to" gorm:"column:to"`
-	ContractAddress string `json:"contract_address" gorm:"column:contract_address"`
-	Value           string `json:"value" gorm:"column:value"`
-	Data            []byte `json:"data" gorm:"column:data"`
-	Gas             uint64 `json:"gas" gorm:"column:gas"`
-	GasPrice        string `json:"gas_price" gorm:"column:gas_price"`
-	Cost            string `json:"cost" gorm:"column:cost"`
-	Nonce           uint64 `json:"nonce" gorm:"column:nonce"`
-	State           uint64 `json:"state" gorm:"column:state"`
-	BlockHash       string `json:"block_hash" gorm:"column:block_hash"`
-	BlockNumber     uint64 `json:"block_number" gorm:"column:block_number"`
-	Timestamp       uint64 `json:"timestamp" gorm:"column:timestamp"`
+	Hash                 string         `json:"hash" gorm:"primaryKey;column:hash"`
+	From                 string         `json:"from" gorm:"column:from;index"`
+	To                   string         `json:"to" gorm:"column:to;index"`
+	ContractAddress      string         `json:"contract_address" gorm:"column:contract_address"`
+	Value                string         `json:"value" gorm:"column:value"`
+	Data                 []byte         `json:"data" gorm:"column:data"`
+	Gas                  uint64         `json:"gas" gorm:"column:gas"`
+	GasPrice             string         `json:"gas_price" gorm:"column:gas_price"`
+	Cost                 string         `json:"cost" gorm:"column:cost"`
+	Nonce                uint64         `json:"nonce" gorm:"column:nonce"`
+	State                uint64         `json:"state" gorm:"column:state"`
+	BlockHash            string         `json:"block_hash" gorm:"column:block_hash"`
+	BlockNumber          uint64         `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp            uint64         `json:"timestamp" gorm:"column:timestamp"`
+	Type                 uint8          `json:"type" gorm:"column:type"`
+	MaxFeePerGas         string         `json:"max_fee_per_gas" gorm:"column:max_fee_per_gas"`
+	MaxPriorityFeePerGas string         `json:"max_priority_fee_per_gas" gorm:"column:max_priority_fee_per_gas"`
+	AccessList           string         `json:"access_list" gorm:"column:access_list"`
+	BlobVersionedHashes  pq.StringArray `json:"blob_versioned_hashes" gorm:"column:blob_versioned_hashes;type:text[]"`
+	MaxFeePerBlobGas     string         `json:"max_fee_per_blob_gas" gorm:"column:max_fee_per_blob_gas"`
+	Receipt              *Receipt       `json:"receipt" gorm:"-"`
+	Decoded              *Decoded       `json:"decoded" gorm:"-"`
 }
 
 type Transactions struct {
@@ -41,13 +50,43 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		data = fmt.Sprintf("0x%s", h)
 	}
 
+	// Access lists are kept as JSON already, which is embedded as is
+	accessList := "null"
+	if t.AccessList != "" {
+		accessList = t.AccessList
+	}
+
+	blobHashes := "[]"
+	if len(t.BlobVersionedHashes) != 0 {
+		blobHashes = strings.Join(strings.Fields(fmt.Sprintf("%q", t.BlobVersionedHashes)), ",")
+	}
+
+	fees := fmt.Sprintf(`,"type":%d,"maxFeePerGas":%q,"maxPriorityFeePerGas":%q,"accessList":%s,"blobVersionedHashes":%s,"maxFeePerBlobGas":%q`,
+		t.Type, t.MaxFeePerGas, t.MaxPriorityFeePerGas, accessList, blobHashes, t.MaxFeePerBlobGas)
+
+	receipt := ""
+
+	if t.Receipt != nil {
+		r, err := t.Receipt.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		receipt = fmt.Sprintf(`,"receipt":%s`, r)
+	}
+
+	decoded, err := decodedField(t.Decoded)
+	if err != nil {
+		return nil, err
+	}
+
 	if !strings.HasPrefix(t.ContractAddress, "0x") {
-		return []byte(fmt.Sprintf(`{"hash":%q,"from":%q,"to":%q,"value":%q,"data":%q,"gas":%d,"gasPrice":%q,"cost":%q,"nonce":%d,"state":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d}"`, t.Hash, t.From, t.To, t.Value, data, t.Gas, t.GasPrice, t.Cost, t.Nonce, t.State, t.BlockHash, t.BlockNumber, t.Timestamp)), nil
+		return []byte(fmt.Sprintf(`{"hash":%q,"from":%q,"to":%q,"value":%q,"data":%q,"gas":%d,"gasPrice":%q,"cost":%q,"nonce":%d,"state":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d%s%s%s}`, t.Hash, t.From, t.To, t.Value, data, t.Gas, t.GasPrice, t.Cost, t.Nonce, t.State, t.BlockHash, t.BlockNumber, t.Timestamp, fees, receipt, decoded)), nil
 	}
 
 	return []byte(fmt.Sprintf(
-		`{hash":%q,"from":%q,"contract_address":%q,"to":%q,"value":%q,"data":%q,"gas":%d,"gasPrice":%q,"cost":%q,"nonce":%d,"state":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d}"`,
-		t.Hash, t.From, t.ContractAddress, t.To, t.Value, data, t.Gas, t.GasPrice, t.Cost, t.Nonce, t.State, t.BlockHash, t.BlockNumber, t.Timestamp)), nil
+		`{"hash":%q,"from":%q,"contract_address":%q,"to":%q,"value":%q,"data":%q,"gas":%d,"gasPrice":%q,"cost":%q,"nonce":%d,"state":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d%s%s%s}`,
+		t.Hash, t.From, t.ContractAddress, t.To, t.Value, data, t.Gas, t.GasPrice, t.Cost, t.Nonce, t.State, t.BlockHash, t.BlockNumber, t.Timestamp, fees, receipt, decoded)), nil
 
 }
 
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// States a webhook delivery goes through. Pending ones are retried with an
+// increasing delay, until they're either delivered or given up on, at which
+// point they're copied to the dead letters.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook has data matching its subscription, written in the same grammar
+// WebSocket subscriptions are, POSTed to its URL. Filter optionally holds a
+// filter object for a bare topic subscription, and Finality the phase blocks
+// must have completed before their data is delivered, latest when empty.
+// Secret signs every delivery, and is only ever shown when the webhook is
+// registered.
+type Webhook struct {
+	ID           uint      `json:"id" gorm:"column:id;primaryKey"`
+	URL          string    `json:"url" gorm:"column:url"`
+	Subscription string    `json:"subscription" gorm:"column:subscription"`
+	Filter       string    `json:"filter" gorm:"column:filter;type:text"`
+	Finality     string    `json:"finality" gorm:"column:finality"`
+	Secret       string    `json:"secret" gorm:"column:secret"`
+	CreatedAt    time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// WebhookDelivery is a payload on its way to a webhook, which doubles as the
+// webhook's delivery log once it's been attempted. The same message is only
+// ever delivered once to a webhook.
+type WebhookDelivery struct {
+	ID            uint      `json:"id" gorm:"column:id;primaryKey"`
+	WebhookID     uint      `json:"webhook_id" gorm:"column:webhook_id;uniqueIndex:idx_webhook_deliveries_message"`
+	Topic         string    `json:"topic" gorm:"column:topic"`
+	MessageID     string    `json:"message_id" gorm:"column:message_id;uniqueIndex:idx_webhook_deliveries_message"`
+	Payload       string    `json:"payload" gorm:"column:payload;type:text"`
+	Status        string    `json:"status" gorm:"column:status;index:idx_webhook_deliveries_due"`
+	Attempts      uint      `json:"attempts" gorm:"column:attempts"`
+	Delay         uint64    `json:"delay" gorm:"column:delay"`
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"column:next_attempt_at;index:idx_webhook_deliveries_due"`
+	ResponseCode  int       `json:"response_code" gorm:"column:response_code"`
+	Error         string    `json:"error" gorm:"column:error;type:text"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+// WebhookDeadLetter keeps a delivery which was given up on, so that it can be
+// looked into or delivered by other means.
+type WebhookDeadLetter struct {
+	ID         uint      `json:"id" gorm:"column:id;primaryKey"`
+	WebhookID  uint      `json:"webhook_id" gorm:"column:webhook_id;index"`
+	DeliveryID uint      `json:"delivery_id" gorm:"column:delivery_id;uniqueIndex"`
+	Topic      string    `json:"topic" gorm:"column:topic"`
+	MessageID  string    `json:"message_id" gorm:"column:message_id"`
+	Payload    string    `json:"payload" gorm:"column:payload;type:text"`
+	Attempts   uint      `json:"attempts" gorm:"column:attempts"`
+	Error      string    `json:"error" gorm:"column:error;type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+type Webhooks struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+type WebhookDeliveries struct {
+	WebhookDeliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+type WebhookDeadLetters struct {
+	WebhookDeadLetters []*WebhookDeadLetter `json:"deadLetters"`
+}
+
+// rawOrNull renders stored JSON as is, or null when there's none.
+func rawOrNull(raw string) string {
+	if raw == "" || !json.Valid([]byte(raw)) {
+		return "null"
+	}
+
+	return raw
+}
+
+func (w *Webhook) MarshalJSON() ([]byte, error) {
+	finality := w.Finality
+	if finality == "" {
+		finality = FinalityLatest
+	}
+
+	return []byte(fmt.Sprintf(`{"id":%d,"url":%q,"subscription":%q,"filter":%s,"finality":%q,"createdAt":%d}`,
+		w.ID,
+		w.URL,
+		w.Subscription,
+		rawOrNull(w.Filter),
+		finality,
+		w.CreatedAt.Unix())), nil
+}
+
+func (w *WebhookDelivery) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"id":%d,"webhookId":%d,"topic":%q,"messageId":%q,"payload":%s,"status":%q,"attempts":%d,"nextAttemptAt":%d,"responseCode":%d,"error":%q,"createdAt":%d,"updatedAt":%d}`,
+		w.ID,
+		w.WebhookID,
+		w.Topic,
+		w.MessageID,
+		rawOrNull(w.Payload),
+		w.Status,
+		w.Attempts,
+		w.NextAttemptAt.Unix(),
+		w.ResponseCode,
+		w.Error,
+		w.CreatedAt.Unix(),
+		w.UpdatedAt.Unix())), nil
+}
+
+func (w *WebhookDeadLetter) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"id":%d,"webhookId":%d,"deliveryId":%d,"topic":%q,"messageId":%q,"payload":%s,"attempts":%d,"error":%q,"createdAt":%d}`,
+		w.ID,
+		w.WebhookID,
+		w.DeliveryID,
+		w.Topic,
+		w.MessageID,
+		rawOrNull(w.Payload),
+		w.Attempts,
+		w.Error,
+		w.CreatedAt.Unix())), nil
+}
+
+func (w *Webhook) ToJSON() []byte {
+	data, err := json.Marshal(w)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling webhook to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (w *Webhooks) ToJSON() []byte {
+	data, err := json.Marshal(w)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling webhooks to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (w *WebhookDeliveries) ToJSON() []byte {
+	data, err := json.Marshal(w)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling webhook deliveries to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (w *WebhookDeadLetters) ToJSON() []byte {
+	data, err := json.Marshal(w)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling webhook dead letters to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
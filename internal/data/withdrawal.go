@@ -0,0 +1,61 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// Withdrawal is a beacon chain validator withdrawal credited to an execution
+// layer address, with its amount in gwei.
+type Withdrawal struct {
+	Index          uint64 `json:"index" gorm:"column:index;primaryKey;autoIncrement:false"`
+	ValidatorIndex uint64 `json:"validator_index" gorm:"column:validator_index;index"`
+	Address        string `json:"address" gorm:"column:address;index"`
+	Amount         uint64 `json:"amount" gorm:"column:amount"`
+	BlockHash      string `json:"block_hash" gorm:"column:block_hash"`
+	BlockNumber    uint64 `json:"block_number" gorm:"column:block_number;index"`
+	Timestamp      uint64 `json:"timestamp" gorm:"column:timestamp"`
+}
+
+type Withdrawals struct {
+	Withdrawals []*Withdrawal `json:"withdrawals"`
+}
+
+func (w *Withdrawal) MarshalBinary() ([]byte, error) {
+	return json.Marshal(w)
+}
+
+func (w *Withdrawal) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"index":%d,"validatorIndex":%d,"address":%q,"amount":%d,"blockHash":%q,"blockNumber":%d,"timestamp":%d}`,
+		w.Index,
+		w.ValidatorIndex,
+		w.Address,
+		w.Amount,
+		w.BlockHash,
+		w.BlockNumber,
+		w.Timestamp)), nil
+}
+
+func (w *Withdrawal) ToJSON() []byte {
+	data, err := json.Marshal(w)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling withdrawal to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
+
+func (ws *Withdrawals) ToJSON() []byte {
+	data, err := json.Marshal(ws)
+
+	if err != nil {
+		logger.S().Errorf("Error marshaling withdrawals to json: %v", err.Error())
+		return nil
+	}
+
+	return data
+}
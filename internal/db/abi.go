@@ -0,0 +1,32 @@
+package db
+
+import (
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+)
+
+func StoreContractABI(_db *gorm.DB, contractABI *data.ContractABI) error {
+	return _db.Create(contractABI).Error
+}
+
+// GetContractABIs returns every uploaded ABI, oldest first, so that later
+// uploads take precedence when they're loaded in order.
+func GetContractABIs(_db *gorm.DB) ([]*data.ContractABI, error) {
+	var abis []*data.ContractABI
+
+	if err := _db.Order("id asc").Find(&abis).Error; err != nil {
+		return nil, err
+	}
+
+	return abis, nil
+}
+
+func GetContractABIsByAddress(_db *gorm.DB, address string) (*data.ContractABIs, error) {
+	var abis []*data.ContractABI
+
+	if err := _db.Where("address = ?", address).Order("id asc").Find(&abis).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.ContractABIs{ContractABIs: abis}, nil
+}
@@ -0,0 +1,198 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoreBlock persists a block along with its transactions and events in a
+// single database transaction. It reports false when a block with the same
+// hash is already present, in which case nothing is written.
+func StoreBlock(_db *gorm.DB, packed *data.PackedBlock) (bool, error) {
+	inserted := false
+
+	err := _db.Transaction(func(dbTx *gorm.DB) error {
+		var stored data.Block
+
+		err := dbTx.Where("hash = ?", packed.Block.Hash).First(&stored).Error
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := deleteBlocksByNumber(dbTx, packed.Block.Number); err != nil {
+			return err
+		}
+
+		if err := dbTx.Create(packed.Block).Error; err != nil {
+			return err
+		}
+
+		if len(packed.Transactions) != 0 {
+			if err := dbTx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(packed.Transactions, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.Receipts) != 0 {
+			if err := dbTx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(packed.Receipts, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.Events) != 0 {
+			if err := dbTx.CreateInBatches(packed.Events, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.Withdrawals) != 0 {
+			if err := dbTx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(packed.Withdrawals, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.InternalTransactions) != 0 {
+			if err := dbTx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(packed.InternalTransactions, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.TokenTransfers) != 0 {
+			if err := dbTx.CreateInBatches(packed.TokenTransfers, 100).Error; err != nil {
+				return err
+			}
+
+			if err := applyTokenTransfers(dbTx, packed.TokenTransfers, 1); err != nil {
+				return err
+			}
+		}
+
+		if len(packed.TokenApprovals) != 0 {
+			if err := dbTx.CreateInBatches(packed.TokenApprovals, 100).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(packed.NFTTransfers) != 0 {
+			if err := dbTx.CreateInBatches(packed.NFTTransfers, 100).Error; err != nil {
+				return err
+			}
+
+			if err := applyNFTTransfers(dbTx, packed.NFTTransfers, 1); err != nil {
+				return err
+			}
+		}
+
+		inserted = true
+		return nil
+	})
+
+	return inserted, err
+}
+
+// deleteBlocksByNumber drops every stored entity at the given height so that
+// a replacement block can be written in its place.
+func deleteBlocksByNumber(dbTx *gorm.DB, number uint64) error {
+	if err := revertTokenTransfers(dbTx, "block_number = ?", number); err != nil {
+		return err
+	}
+
+	if err := revertNFTTransfers(dbTx, "block_number = ?", number); err != nil {
+		return err
+	}
+
+	if err := dbTx.Where("block_number = ?", number).Delete(&data.Event{}).Error; err != nil {
+		return err
+	}
+
+	if err := dbTx.Where("block_number = ?", number).Delete(&data.InternalTransaction{}).Error; err != nil {
+		return err
+	}
+
+	if err := dbTx.Where("block_number = ?", number).Delete(&data.Withdrawal{}).Error; err != nil {
+		return err
+	}
+
+	if err := dbTx.Where("block_number = ?", number).Delete(&data.Receipt{}).Error; err != nil {
+		return err
+	}
+
+	if err := dbTx.Where("block_number = ?", number).Delete(&data.Transaction{}).Error; err != nil {
+		return err
+	}
+
+	return dbTx.Where("number = ?", number).Delete(&data.Block{}).Error
+}
+
+func GetBlockByNumber(_db *gorm.DB, number uint64) (*data.Block, error) {
+	var block data.Block
+
+	if err := _db.Where("number = ?", number).First(&block).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// DeleteBlocksAbove removes every block, transaction and event stored above
+// the given height and returns the blocks which were removed, lowest first.
+func DeleteBlocksAbove(_db *gorm.DB, number uint64) ([]*data.Block, error) {
+	var orphaned []*data.Block
+
+	err := _db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Where("number > ?", number).Order("number asc").Find(&orphaned).Error; err != nil {
+			return err
+		}
+
+		if len(orphaned) == 0 {
+			return nil
+		}
+
+		if err := revertTokenTransfers(dbTx, "block_number > ?", number); err != nil {
+			return err
+		}
+
+		if err := revertNFTTransfers(dbTx, "block_number > ?", number); err != nil {
+			return err
+		}
+
+		if err := dbTx.Where("block_number > ?", number).Delete(&data.Event{}).Error; err != nil {
+			return err
+		}
+
+		if err := dbTx.Where("block_number > ?", number).Delete(&data.InternalTransaction{}).Error; err != nil {
+			return err
+		}
+
+		if err := dbTx.Where("block_number > ?", number).Delete(&data.Withdrawal{}).Error; err != nil {
+			return err
+		}
+
+		if err := dbTx.Where("block_number > ?", number).Delete(&data.Receipt{}).Error; err != nil {
+			return err
+		}
+
+		if err := dbTx.Where("block_number > ?", number).Delete(&data.Transaction{}).Error; err != nil {
+			return err
+		}
+
+		return dbTx.Where("number > ?", number).Delete(&data.Block{}).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orphaned, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func Connect() *gorm.DB {
+	log := logger.S()
+
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_NAME"))
+
+	_db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:                 gormlogger.Default.LogMode(gormlogger.Silent),
+		SkipDefaultTransaction: true,
+	})
+
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %s\n", err.Error())
+	}
+
+	if err := Migrate(_db); err != nil {
+		log.Fatalf("Failed to migrate database: %s\n", err.Error())
+	}
+
+	return _db
+}
+
+func Migrate(_db *gorm.DB) error {
+	return _db.AutoMigrate(
+		&data.Block{},
+		&data.Transaction{},
+		&data.Receipt{},
+		&data.Event{},
+		&data.Withdrawal{},
+		&data.InternalTransaction{},
+		&data.Token{},
+		&data.TokenTransfer{},
+		&data.TokenApproval{},
+		&data.TokenBalance{},
+		&data.NFTTransfer{},
+		&data.NFTOwner{},
+		&data.ContractABI{},
+		&data.Webhook{},
+		&data.WebhookDelivery{},
+		&data.WebhookDeadLetter{},
+		&data.SyncCheckpoint{},
+	)
+}
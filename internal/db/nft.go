@@ -0,0 +1,142 @@
+package db
+
+import (
+	"math/big"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ownershipDeltas nets the given transfers into a change of amount held per
+// token and owner. Mints and burns aren't credited to the zero address.
+func ownershipDeltas(transfers []*data.NFTTransfer, sign int64) []*data.NFTOwner {
+	type key struct{ contract, id, owner string }
+
+	deltas := make(map[key]*data.NFTOwner)
+	amounts := make(map[key]*big.Int)
+	order := make([]key, 0)
+
+	apply := func(k key, transfer *data.NFTTransfer, amount *big.Int) {
+		if util.IsZeroAddress(k.owner) {
+			return
+		}
+
+		if _, ok := deltas[k]; !ok {
+			deltas[k] = &data.NFTOwner{
+				Contract: k.contract,
+				TokenID:  k.id,
+				Owner:    k.owner,
+				Standard: transfer.Standard,
+			}
+			amounts[k] = new(big.Int)
+			order = append(order, k)
+		}
+
+		amounts[k].Add(amounts[k], amount)
+
+		if transfer.BlockNumber > deltas[k].BlockNumber {
+			deltas[k].BlockNumber = transfer.BlockNumber
+		}
+	}
+
+	for _, transfer := range transfers {
+		amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+		if !ok {
+			continue
+		}
+
+		amount.Mul(amount, big.NewInt(sign))
+
+		apply(key{transfer.Contract, transfer.TokenID, transfer.To}, transfer, amount)
+		apply(key{transfer.Contract, transfer.TokenID, transfer.From}, transfer, new(big.Int).Neg(amount))
+	}
+
+	owners := make([]*data.NFTOwner, 0, len(order))
+
+	for _, k := range order {
+		if amounts[k].Sign() == 0 {
+			continue
+		}
+
+		deltas[k].Amount = amounts[k].String()
+		owners = append(owners, deltas[k])
+	}
+
+	return owners
+}
+
+// applyNFTTransfers moves ownership by the given transfers, or back when
+// sign is negative.
+func applyNFTTransfers(dbTx *gorm.DB, transfers []*data.NFTTransfer, sign int64) error {
+	owners := ownershipDeltas(transfers, sign)
+	if len(owners) == 0 {
+		return nil
+	}
+
+	return dbTx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "contract"}, {Name: "token_id"}, {Name: "owner"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"amount":       gorm.Expr("nft_owners.amount + excluded.amount"),
+			"block_number": gorm.Expr("GREATEST(nft_owners.block_number, excluded.block_number)"),
+		}),
+	}).CreateInBatches(owners, 100).Error
+}
+
+// revertNFTTransfers undoes the ownership changes of transfers matching the
+// given condition, ahead of them being deleted.
+func revertNFTTransfers(dbTx *gorm.DB, query string, args ...interface{}) error {
+	var transfers []*data.NFTTransfer
+
+	if err := dbTx.Where(query, args...).Find(&transfers).Error; err != nil {
+		return err
+	}
+
+	if err := applyNFTTransfers(dbTx, transfers, -1); err != nil {
+		return err
+	}
+
+	return dbTx.Where(query, args...).Delete(&data.NFTTransfer{}).Error
+}
+
+// GetNFTHoldings returns tokens currently held by an owner, optionally of a
+// single contract.
+func GetNFTHoldings(_db *gorm.DB, owner string, contract string) (*data.NFTOwners, error) {
+	var owners []*data.NFTOwner
+
+	query := _db.Where("owner = ? AND amount > 0", owner)
+
+	if contract != "" {
+		query = query.Where("contract = ?", contract)
+	}
+
+	if err := query.Order("contract asc").Order("token_id asc").Find(&owners).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.NFTOwners{NFTOwners: owners}, nil
+}
+
+// GetNFTOwners returns current owners of a token, of which ERC-721 ones
+// have a single one.
+func GetNFTOwners(_db *gorm.DB, contract string, id string) (*data.NFTOwners, error) {
+	var owners []*data.NFTOwner
+
+	if err := _db.Where("contract = ? AND token_id = ? AND amount > 0", contract, id).Order("owner asc").Find(&owners).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.NFTOwners{NFTOwners: owners}, nil
+}
+
+// GetNFTProvenance returns every transfer of a token, oldest first.
+func GetNFTProvenance(_db *gorm.DB, contract string, id string) (*data.NFTTransfers, error) {
+	var transfers []*data.NFTTransfer
+
+	if err := _db.Where("contract = ? AND token_id = ?", contract, id).Order("block_number asc").Order("log_index asc").Order("batch_index asc").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.NFTTransfers{NFTTransfers: transfers}, nil
+}
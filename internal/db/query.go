@@ -0,0 +1,234 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+)
+
+func GetBlockByHash(_db *gorm.DB, hash string) (*data.Block, error) {
+	var block data.Block
+
+	if err := _db.Where("hash = ?", hash).First(&block).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+func GetBlocksByNumberRange(_db *gorm.DB, from uint64, to uint64) (*data.Blocks, error) {
+	var blocks []*data.Block
+
+	if err := _db.Where("number >= ? AND number <= ?", from, to).Order("number asc").Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.Blocks{Blocks: blocks}, nil
+}
+
+func GetTransactionByHash(_db *gorm.DB, hash string) (*data.Transaction, error) {
+	var tx data.Transaction
+
+	if err := _db.Where("hash = ?", hash).First(&tx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+// GetTransactionsByBlockNumberRange returns transactions in the block range,
+// optionally narrowed down to a sender and/or recipient. Empty addresses
+// aren't filtered on.
+func GetTransactionsByBlockNumberRange(_db *gorm.DB, from string, to string, fromBlock uint64, toBlock uint64) (*data.Transactions, error) {
+	var txs []*data.Transaction
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if from != "" {
+		query = query.Where(`"from" = ?`, from)
+	}
+
+	if to != "" {
+		query = query.Where(`("to" = ? OR contract_address = ?)`, to, to)
+	}
+
+	if err := query.Order("block_number asc").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.Transactions{Transactions: txs}, nil
+}
+
+// GetEventsByBlockNumberRange returns events emitted in the block range,
+// optionally narrowed down to an emitting contract and topics, keyed by
+// their position in the log.
+func GetEventsByBlockNumberRange(_db *gorm.DB, contract string, topics map[uint8]string, fromBlock uint64, toBlock uint64) (*data.Events, error) {
+	var events []*data.Event
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if contract != "" {
+		query = query.Where("origin = ?", contract)
+	}
+
+	for i, topic := range topics {
+		// Postgres arrays are 1-indexed
+		query = query.Where(fmt.Sprintf("topics[%d] = ?", i+1), topic)
+	}
+
+	if err := query.Order("block_number asc").Order(`"index" asc`).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.Events{Events: events}, nil
+}
+
+func GetReceiptByTransactionHash(_db *gorm.DB, hash string) (*data.Receipt, error) {
+	var receipt data.Receipt
+
+	if err := _db.Where("transaction_hash = ?", hash).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+// AttachReceipts looks up the receipts of the given transactions and sets
+// them on each one which has a receipt stored.
+func AttachReceipts(_db *gorm.DB, txs []*data.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+
+	var receipts []*data.Receipt
+
+	if err := _db.Where("transaction_hash IN ?", hashes).Find(&receipts).Error; err != nil {
+		return err
+	}
+
+	byHash := make(map[string]*data.Receipt, len(receipts))
+	for _, receipt := range receipts {
+		byHash[receipt.TransactionHash] = receipt
+	}
+
+	for _, tx := range txs {
+		tx.Receipt = byHash[tx.Hash]
+	}
+
+	return nil
+}
+
+// GetWithdrawalsByBlockNumberRange returns withdrawals in the block range,
+// optionally narrowed down to a recipient address and/or validator.
+func GetWithdrawalsByBlockNumberRange(_db *gorm.DB, address string, validator *uint64, fromBlock uint64, toBlock uint64) (*data.Withdrawals, error) {
+	var withdrawals []*data.Withdrawal
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if address != "" {
+		query = query.Where("address = ?", address)
+	}
+
+	if validator != nil {
+		query = query.Where("validator_index = ?", *validator)
+	}
+
+	if err := query.Order(`"index" asc`).Find(&withdrawals).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.Withdrawals{Withdrawals: withdrawals}, nil
+}
+
+// GetInternalTransactionsByTransactionHash returns the calls made while
+// executing a transaction, in the order they were made.
+func GetInternalTransactionsByTransactionHash(_db *gorm.DB, hash string) (*data.InternalTransactions, error) {
+	var txs []*data.InternalTransaction
+
+	if err := _db.Where("transaction_hash = ?", hash).Order("trace_address asc").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.InternalTransactions{InternalTransactions: txs}, nil
+}
+
+// GetInternalTransactionsByBlockNumberRange returns internal transactions in
+// the block range, sent from and/or to the given addresses.
+func GetInternalTransactionsByBlockNumberRange(_db *gorm.DB, from string, to string, fromBlock uint64, toBlock uint64) (*data.InternalTransactions, error) {
+	var txs []*data.InternalTransaction
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if from != "" {
+		query = query.Where(`"from" = ?`, from)
+	}
+
+	if to != "" {
+		query = query.Where(`"to" = ?`, to)
+	}
+
+	if err := query.Order("block_number asc").Order("transaction_hash asc").Order("trace_address asc").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.InternalTransactions{InternalTransactions: txs}, nil
+}
+
+// GetPackedBlock loads the stored block at the given height along with its
+// transactions, events and withdrawals, the way they're published. It's nil
+// when no block is stored there.
+func GetPackedBlock(_db *gorm.DB, number uint64) (*data.PackedBlock, error) {
+	blocks, err := GetBlocksByNumberRange(_db, number, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks.Blocks) == 0 {
+		return nil, nil
+	}
+
+	txs, err := GetTransactionsByBlockNumberRange(_db, "", "", number, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := AttachReceipts(_db, txs.Transactions); err != nil {
+		return nil, err
+	}
+
+	events, err := GetEventsByBlockNumberRange(_db, "", map[uint8]string{}, number, number)
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals, err := GetWithdrawalsByBlockNumberRange(_db, "", nil, number, number)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.PackedBlock{
+		Block:        blocks.Blocks[0],
+		Transactions: txs.Transactions,
+		Events:       events.Events,
+		Withdrawals:  withdrawals.Withdrawals,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+)
+
+func GetBlockCount(_db *gorm.DB) uint64 {
+	var count int64
+
+	if err := _db.Model(&data.Block{}).Count(&count).Error; err != nil {
+		return 0
+	}
+
+	return uint64(count)
+}
+
+func GetCurrentBlockNumber(_db *gorm.DB) uint64 {
+	var number uint64
+
+	if err := _db.Model(&data.Block{}).Select("coalesce(max(number), 0)").Scan(&number).Error; err != nil {
+		return 0
+	}
+
+	return number
+}
+
+func GetCurrentOldestBlockNumber(_db *gorm.DB) uint64 {
+	var number uint64
+
+	if err := _db.Model(&data.Block{}).Select("coalesce(min(number), 0)").Scan(&number).Error; err != nil {
+		return 0
+	}
+
+	return number
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetMissingBlockNumbers returns up to limit block numbers in [from, to]
+// which aren't stored yet, in ascending order.
+func GetMissingBlockNumbers(_db *gorm.DB, from uint64, to uint64, limit int) ([]uint64, error) {
+	var numbers []uint64
+
+	err := _db.Raw(`SELECT n FROM generate_series(?::bigint, ?::bigint) AS n
+		WHERE NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.number = n)
+		ORDER BY n
+		LIMIT ?`, from, to, limit).Scan(&numbers).Error
+
+	return numbers, err
+}
+
+func CountBlocksInRange(_db *gorm.DB, from uint64, to uint64) (uint64, error) {
+	var count int64
+
+	err := _db.Model(&data.Block{}).Where("number >= ? AND number <= ?", from, to).Count(&count).Error
+	return uint64(count), err
+}
+
+func GetCheckpoint(_db *gorm.DB, name string) (*data.SyncCheckpoint, error) {
+	var checkpoint data.SyncCheckpoint
+
+	if err := _db.Where("name = ?", name).First(&checkpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+func SaveCheckpoint(_db *gorm.DB, checkpoint *data.SyncCheckpoint) error {
+	return _db.Clauses(clause.OnConflict{UpdateAll: true}).Create(checkpoint).Error
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetTokensByAddress returns metadata of those of the given tokens which are
+// known, keyed by address.
+func GetTokensByAddress(_db *gorm.DB, addresses []string) (map[string]*data.Token, error) {
+	tokens := make(map[string]*data.Token)
+
+	if len(addresses) == 0 {
+		return tokens, nil
+	}
+
+	var found []*data.Token
+
+	if err := _db.Where("address IN ?", addresses).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	for _, token := range found {
+		tokens[token.Address] = token
+	}
+
+	return tokens, nil
+}
+
+func GetTokenByAddress(_db *gorm.DB, address string) (*data.Token, error) {
+	var token data.Token
+
+	if err := _db.Where("address = ?", address).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func StoreTokens(_db *gorm.DB, tokens []*data.Token) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	return _db.Clauses(clause.OnConflict{DoNothing: true}).Create(tokens).Error
+}
+
+// balanceDeltas nets the given transfers into a change of balance per token
+// and holder. Mints and burns aren't credited to the zero address.
+func balanceDeltas(transfers []*data.TokenTransfer, sign int64) []*data.TokenBalance {
+	type key struct{ token, holder string }
+
+	deltas := make(map[key]*big.Int)
+	blocks := make(map[key]uint64)
+	order := make([]key, 0)
+
+	apply := func(k key, value *big.Int, number uint64) {
+		if util.IsZeroAddress(k.holder) {
+			return
+		}
+
+		if _, ok := deltas[k]; !ok {
+			deltas[k] = new(big.Int)
+			order = append(order, k)
+		}
+
+		deltas[k].Add(deltas[k], value)
+
+		if number > blocks[k] {
+			blocks[k] = number
+		}
+	}
+
+	for _, transfer := range transfers {
+		value, ok := new(big.Int).SetString(transfer.Value, 10)
+		if !ok {
+			continue
+		}
+
+		value.Mul(value, big.NewInt(sign))
+
+		apply(key{transfer.Token, transfer.To}, value, transfer.BlockNumber)
+		apply(key{transfer.Token, transfer.From}, new(big.Int).Neg(value), transfer.BlockNumber)
+	}
+
+	balances := make([]*data.TokenBalance, 0, len(order))
+
+	for _, k := range order {
+		if deltas[k].Sign() == 0 {
+			continue
+		}
+
+		balances = append(balances, &data.TokenBalance{
+			Token:       k.token,
+			Holder:      k.holder,
+			Balance:     deltas[k].String(),
+			BlockNumber: blocks[k],
+		})
+	}
+
+	return balances
+}
+
+// applyTokenTransfers moves balances by the given transfers, or back when
+// sign is negative.
+func applyTokenTransfers(dbTx *gorm.DB, transfers []*data.TokenTransfer, sign int64) error {
+	balances := balanceDeltas(transfers, sign)
+	if len(balances) == 0 {
+		return nil
+	}
+
+	return dbTx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "token"}, {Name: "holder"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"balance":      gorm.Expr("token_balances.balance + excluded.balance"),
+			"block_number": gorm.Expr("GREATEST(token_balances.block_number, excluded.block_number)"),
+		}),
+	}).CreateInBatches(balances, 100).Error
+}
+
+// revertTokenTransfers undoes the balance changes of transfers matching the
+// given condition, ahead of them being deleted.
+func revertTokenTransfers(dbTx *gorm.DB, query string, args ...interface{}) error {
+	var transfers []*data.TokenTransfer
+
+	if err := dbTx.Where(query, args...).Find(&transfers).Error; err != nil {
+		return err
+	}
+
+	if err := applyTokenTransfers(dbTx, transfers, -1); err != nil {
+		return err
+	}
+
+	if err := dbTx.Where(query, args...).Delete(&data.TokenTransfer{}).Error; err != nil {
+		return err
+	}
+
+	return dbTx.Where(query, args...).Delete(&data.TokenApproval{}).Error
+}
+
+// GetTokenTransfersByBlockNumberRange returns transfers in the block range,
+// optionally narrowed down to a token, sender and/or recipient.
+func GetTokenTransfersByBlockNumberRange(_db *gorm.DB, token string, from string, to string, fromBlock uint64, toBlock uint64) (*data.TokenTransfers, error) {
+	var transfers []*data.TokenTransfer
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if token != "" {
+		query = query.Where("token = ?", token)
+	}
+
+	if from != "" {
+		query = query.Where(`"from" = ?`, from)
+	}
+
+	if to != "" {
+		query = query.Where(`"to" = ?`, to)
+	}
+
+	if err := query.Order("block_number asc").Order("log_index asc").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+
+	tokens, err := getTokensOf(_db, len(transfers), func(i int) string { return transfers[i].Token })
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transfer := range transfers {
+		transfer.Metadata = tokens[transfer.Token]
+	}
+
+	return &data.TokenTransfers{TokenTransfers: transfers}, nil
+}
+
+// GetTokenApprovalsByBlockNumberRange returns approvals in the block range,
+// optionally narrowed down to a token, owner and/or spender.
+func GetTokenApprovalsByBlockNumberRange(_db *gorm.DB, token string, owner string, spender string, fromBlock uint64, toBlock uint64) (*data.TokenApprovals, error) {
+	var approvals []*data.TokenApproval
+
+	query := _db.Where("block_number >= ? AND block_number <= ?", fromBlock, toBlock)
+
+	if token != "" {
+		query = query.Where("token = ?", token)
+	}
+
+	if owner != "" {
+		query = query.Where("owner = ?", owner)
+	}
+
+	if spender != "" {
+		query = query.Where("spender = ?", spender)
+	}
+
+	if err := query.Order("block_number asc").Order("log_index asc").Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+
+	tokens, err := getTokensOf(_db, len(approvals), func(i int) string { return approvals[i].Token })
+	if err != nil {
+		return nil, err
+	}
+
+	for _, approval := range approvals {
+		approval.Metadata = tokens[approval.Token]
+	}
+
+	return &data.TokenApprovals{TokenApprovals: approvals}, nil
+}
+
+// GetTokenBalances returns non-zero balances of a holder, optionally of a
+// single token.
+func GetTokenBalances(_db *gorm.DB, holder string, token string) (*data.TokenBalances, error) {
+	var balances []*data.TokenBalance
+
+	query := _db.Where("holder = ? AND balance <> 0", holder)
+
+	if token != "" {
+		query = query.Where("token = ?", token)
+	}
+
+	if err := query.Order("token asc").Find(&balances).Error; err != nil {
+		return nil, err
+	}
+
+	tokens, err := getTokensOf(_db, len(balances), func(i int) string { return balances[i].Token })
+	if err != nil {
+		return nil, err
+	}
+
+	for _, balance := range balances {
+		balance.Metadata = tokens[balance.Token]
+	}
+
+	return &data.TokenBalances{TokenBalances: balances}, nil
+}
+
+func getTokensOf(_db *gorm.DB, n int, address func(int) string) (map[string]*data.Token, error) {
+	addresses := make([]string, 0, n)
+	seen := make(map[string]bool)
+
+	for i := 0; i < n; i++ {
+		if !seen[address(i)] {
+			seen[address(i)] = true
+			addresses = append(addresses, address(i))
+		}
+	}
+
+	return GetTokensByAddress(_db, addresses)
+}
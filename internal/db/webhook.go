@@ -0,0 +1,181 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func StoreWebhook(_db *gorm.DB, webhook *data.Webhook) error {
+	return _db.Create(webhook).Error
+}
+
+func GetWebhooks(_db *gorm.DB) ([]*data.Webhook, error) {
+	var webhooks []*data.Webhook
+
+	if err := _db.Order("id asc").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func GetWebhookByID(_db *gorm.DB, id uint) (*data.Webhook, error) {
+	var webhook data.Webhook
+
+	if err := _db.Where("id = ?", id).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook along with its deliveries and dead
+// letters, reporting whether it existed.
+func DeleteWebhook(_db *gorm.DB, id uint) (bool, error) {
+	deleted := false
+
+	err := _db.Transaction(func(dbTx *gorm.DB) error {
+		result := dbTx.Where("id = ?", id).Delete(&data.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		deleted = result.RowsAffected != 0
+
+		if err := dbTx.Where("webhook_id = ?", id).Delete(&data.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return dbTx.Where("webhook_id = ?", id).Delete(&data.WebhookDeadLetter{}).Error
+	})
+
+	return deleted, err
+}
+
+// StoreWebhookDeliveries queues deliveries, skipping messages which were
+// queued for the same webhook before.
+func StoreWebhookDeliveries(_db *gorm.DB, deliveries []*data.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return _db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(deliveries, 100).Error
+}
+
+// ClaimDueWebhookDeliveries returns pending deliveries whose next attempt is
+// due, those waiting longest first, pushing their next attempt back by the
+// lease. Other dispatchers skip them meanwhile, and pick them up again once
+// the lease is over should this one never record how they went.
+func ClaimDueWebhookDeliveries(_db *gorm.DB, now time.Time, lease time.Duration, limit int) ([]*data.WebhookDelivery, error) {
+	var deliveries []*data.WebhookDelivery
+
+	err := _db.Transaction(func(dbTx *gorm.DB) error {
+		if err := dbTx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Where("status = ? and next_attempt_at <= ?", data.DeliveryPending, now).Order("next_attempt_at asc").Limit(limit).Find(&deliveries).Error; err != nil {
+			return err
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			delivery.NextAttemptAt = now.Add(lease)
+			ids = append(ids, delivery.ID)
+		}
+
+		return dbTx.Model(&data.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery records how an attempt went. A delivery which was
+// deleted meanwhile, along with its webhook, stays deleted.
+func UpdateWebhookDelivery(_db *gorm.DB, delivery *data.WebhookDelivery) error {
+	_, err := updateWebhookDelivery(_db, delivery)
+	return err
+}
+
+func updateWebhookDelivery(_db *gorm.DB, delivery *data.WebhookDelivery) (bool, error) {
+	result := _db.Model(&data.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"delay":           delivery.Delay,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_code":   delivery.ResponseCode,
+		"error":           delivery.Error,
+	})
+
+	return result.RowsAffected != 0, result.Error
+}
+
+// DeleteWebhookDeliveries removes deliveries by their IDs.
+func DeleteWebhookDeliveries(_db *gorm.DB, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return _db.Where("id IN ?", ids).Delete(&data.WebhookDelivery{}).Error
+}
+
+// DeadLetterWebhookDelivery gives up on a delivery, copying it to the dead
+// letters, unless it was deleted meanwhile.
+func DeadLetterWebhookDelivery(_db *gorm.DB, delivery *data.WebhookDelivery) error {
+	return _db.Transaction(func(dbTx *gorm.DB) error {
+		delivery.Status = data.DeliveryDead
+
+		updated, err := updateWebhookDelivery(dbTx, delivery)
+		if err != nil || !updated {
+			return err
+		}
+
+		return dbTx.Clauses(clause.OnConflict{DoNothing: true}).Create(&data.WebhookDeadLetter{
+			WebhookID:  delivery.WebhookID,
+			DeliveryID: delivery.ID,
+			Topic:      delivery.Topic,
+			MessageID:  delivery.MessageID,
+			Payload:    delivery.Payload,
+			Attempts:   delivery.Attempts,
+			Error:      delivery.Error,
+		}).Error
+	})
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, optionally
+// only those in the given status.
+func GetWebhookDeliveries(_db *gorm.DB, webhookID uint, status string, limit int) (*data.WebhookDeliveries, error) {
+	var deliveries []*data.WebhookDelivery
+
+	query := _db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.WebhookDeliveries{WebhookDeliveries: deliveries}, nil
+}
+
+func GetWebhookDeadLetters(_db *gorm.DB, webhookID uint, limit int) (*data.WebhookDeadLetters, error) {
+	var deadLetters []*data.WebhookDeadLetter
+
+	if err := _db.Where("webhook_id = ?", webhookID).Order("id desc").Limit(limit).Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+
+	return &data.WebhookDeadLetters{WebhookDeadLetters: deadLetters}, nil
+}
@@ -0,0 +1,124 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// KafkaMessageIDHeader carries the message ID, so that consumers can drop
+// data which got published again, e.g. when a block is processed twice.
+const KafkaMessageIDHeader = "nyx-message-id"
+
+// Headers carrying the finality of the message's block, and how many
+// confirmations it had when published.
+const (
+	KafkaFinalityHeader      = "nyx-finality"
+	KafkaConfirmationsHeader = "nyx-confirmations"
+)
+
+// Kafka publishes every topic to a Kafka topic of the same name, after a
+// prefix, keying messages so that related ones land on the same partition.
+// Data of blocks past latest goes to a topic with the finality appended,
+// e.g. `nyx.block.finalized`.
+type Kafka struct {
+	Producer     sarama.SyncProducer
+	Prefix       string
+	PartitionKey string
+}
+
+// KafkaConfig returns the producer configuration. An idempotent producer has
+// the brokers drop messages it sent twice while retrying, which needs every
+// in-sync replica to acknowledge writes and a single request in flight per
+// broker to keep them in order.
+func KafkaConfig(clientID string, idempotent bool) *sarama.Config {
+	config := sarama.NewConfig()
+
+	config.ClientID = clientID
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+
+	if idempotent {
+		config.Producer.Idempotent = true
+		config.Producer.RequiredAcks = sarama.WaitForAll
+		config.Producer.Retry.Max = 10
+		config.Net.MaxOpenRequests = 1
+	}
+
+	return config
+}
+
+func NewKafka(producer sarama.SyncProducer, prefix string, partitionKey string) *Kafka {
+	return &Kafka{
+		Producer:     producer,
+		Prefix:       prefix,
+		PartitionKey: partitionKey,
+	}
+}
+
+func kafkaFromEnv() (*Kafka, error) {
+	brokers := envOr("KAFKA_BROKERS", "")
+	if brokers == "" {
+		return nil, errors.New("KAFKA_BROKERS is required to publish to Kafka")
+	}
+
+	idempotent, err := envBool("KAFKA_IDEMPOTENT", true)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionKey, err := envPartitionKey("KAFKA_PARTITION_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	config := KafkaConfig(envOr("KAFKA_CLIENT_ID", "nyx"), idempotent)
+
+	if v := envOr("KAFKA_VERSION", ""); v != "" {
+		version, err := sarama.ParseKafkaVersion(v)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Version = version
+	}
+
+	producer, err := sarama.NewSyncProducer(strings.Split(brokers, ","), config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKafka(producer, envOr("KAFKA_TOPIC_PREFIX", "nyx."), partitionKey), nil
+}
+
+func (k *Kafka) Publish(ctx context.Context, messages ...*data.Message) error {
+	batch := make([]*sarama.ProducerMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		value, err := msg.Data.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, &sarama.ProducerMessage{
+			Topic: k.Prefix + data.FinalityTopic(msg.Topic, msg.Finality),
+			Key:   sarama.StringEncoder(partitionKey(k.PartitionKey, msg)),
+			Value: sarama.ByteEncoder(value),
+			Headers: []sarama.RecordHeader{
+				{Key: []byte(KafkaMessageIDHeader), Value: []byte(msg.ID)},
+				{Key: []byte(KafkaFinalityHeader), Value: []byte(msg.Finality)},
+				{Key: []byte(KafkaConfirmationsHeader), Value: []byte(strconv.FormatUint(msg.Confirmations, 10))},
+			},
+		})
+	}
+
+	return k.Producer.SendMessages(batch)
+}
+
+func (k *Kafka) Close() error {
+	return k.Producer.Close()
+}
@@ -0,0 +1,146 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/nats-io/nats.go"
+)
+
+// Headers carrying the finality of the message's block, and how many
+// confirmations it had when published.
+const (
+	NATSFinalityHeader      = "Nyx-Finality"
+	NATSConfirmationsHeader = "Nyx-Confirmations"
+)
+
+// jetStream is the part of a JetStream context messages are published with.
+type jetStream interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// NATS publishes to NATS JetStream, on a subject made of the prefix, the
+// topic and the partition key, e.g. `nyx.event.0xabc...`, so that consumers
+// can pick the partitions they want with subject wildcards. Data of blocks
+// past latest has the finality after the topic, e.g. `nyx.event.safe.0xabc...`,
+// and every message carries the Nyx-Finality and Nyx-Confirmations headers.
+//
+// With deduplication on, the message ID is set as the Nats-Msg-Id header,
+// and the stream drops messages published again within its duplicates
+// window.
+type NATS struct {
+	Conn         *nats.Conn
+	JetStream    jetStream
+	Prefix       string
+	PartitionKey string
+	Deduplicate  bool
+}
+
+func NewNATS(conn *nats.Conn, js jetStream, prefix string, partitionKey string, deduplicate bool) *NATS {
+	return &NATS{
+		Conn:         conn,
+		JetStream:    js,
+		Prefix:       prefix,
+		PartitionKey: partitionKey,
+		Deduplicate:  deduplicate,
+	}
+}
+
+func natsFromEnv() (*NATS, error) {
+	deduplicate, err := envBool("NATS_DEDUPLICATE", true)
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := envDuration("NATS_DEDUPLICATION_WINDOW", time.Duration(2)*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	partitionKey, err := envPartitionKey("NATS_PARTITION_KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := nats.Connect(envOr("NATS_URL", nats.DefaultURL))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	prefix := envOr("NATS_SUBJECT_PREFIX", "nyx.")
+
+	if stream := envOr("NATS_STREAM", ""); stream != "" {
+		if err := ensureStream(js, stream, prefix, window); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return NewNATS(conn, js, prefix, partitionKey, deduplicate), nil
+}
+
+// ensureStream creates the stream capturing every subject published to,
+// unless it exists already.
+func ensureStream(js nats.JetStreamContext, name string, prefix string, window time.Duration) error {
+	_, err := js.StreamInfo(name)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return err
+	}
+
+	subjects := make([]string, 0, 5)
+	for _, topic := range []string{data.BlockTopic, data.TransactionTopic, data.EventTopic, data.WithdrawalTopic, data.ReorgTopic} {
+		subjects = append(subjects, prefix+topic+".>")
+	}
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:       name,
+		Subjects:   subjects,
+		Duplicates: window,
+	})
+	return err
+}
+
+func (n *NATS) Publish(ctx context.Context, messages ...*data.Message) error {
+	for _, msg := range messages {
+		payload, err := msg.Data.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		m := nats.NewMsg(n.Prefix + data.FinalityTopic(msg.Topic, msg.Finality) + "." + partitionKey(n.PartitionKey, msg))
+		m.Data = payload
+		m.Header.Set(NATSFinalityHeader, msg.Finality)
+		m.Header.Set(NATSConfirmationsHeader, strconv.FormatUint(msg.Confirmations, 10))
+
+		if n.Deduplicate {
+			m.Header.Set(nats.MsgIdHdr, msg.ID)
+		}
+
+		if _, err := n.JetStream.PublishMsg(m, nats.Context(ctx)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *NATS) Close() error {
+	if n.Conn == nil {
+		return nil
+	}
+
+	return n.Conn.Drain()
+}
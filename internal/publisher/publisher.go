@@ -0,0 +1,219 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// Ways messages can be keyed, which decides the partition or subject they
+// end up on. Keying by block number keeps every message of a block together,
+// keying by contract keeps the activity of each contract in order.
+const (
+	BlockNumberKey = "block"
+	ContractKey    = "contract"
+)
+
+// partitionKey returns what the message is keyed by. Messages which don't
+// involve any contract, such as blocks, fall back to their block number.
+func partitionKey(strategy string, msg *data.Message) string {
+	if strategy == ContractKey && msg.Contract != "" {
+		return strings.ToLower(msg.Contract)
+	}
+
+	return strconv.FormatUint(msg.BlockNumber, 10)
+}
+
+// Multi publishes every message to all of its publishers, e.g. Redis for the
+// WebSocket API along with Kafka for the data platform.
+//
+// When some publishers fail, the block is published again later on. Multi
+// remembers which publishers did send each message meanwhile, so that the
+// retry only goes to those which failed.
+type Multi struct {
+	Publishers []data.Publisher
+
+	lock sync.Mutex
+	// sent holds, by message ID, the publishers which sent messages others
+	// failed to
+	sent map[string]map[int]bool
+}
+
+func NewMulti(publishers ...data.Publisher) *Multi {
+	return &Multi{
+		Publishers: publishers,
+		sent:       make(map[string]map[int]bool),
+	}
+}
+
+func (m *Multi) Publish(ctx context.Context, messages ...*data.Message) error {
+	errs := make([]error, 0)
+	succeeded := make([]int, 0, len(m.Publishers))
+
+	for i, publisher := range m.Publishers {
+		pending := m.pending(i, messages)
+		if len(pending) == 0 {
+			continue
+		}
+
+		if err := publisher.Publish(ctx, pending...); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		succeeded = append(succeeded, i)
+	}
+
+	m.record(messages, succeeded, len(errs) == 0)
+
+	return errors.Join(errs...)
+}
+
+// pending returns the messages the publisher hasn't sent yet.
+func (m *Multi) pending(publisher int, messages []*data.Message) []*data.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pending := make([]*data.Message, 0, len(messages))
+
+	for _, msg := range messages {
+		if !m.sent[msg.ID][publisher] {
+			pending = append(pending, msg)
+		}
+	}
+
+	return pending
+}
+
+// record remembers which publishers sent the messages, forgetting about
+// them once every publisher has.
+func (m *Multi) record(messages []*data.Message, succeeded []int, done bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, msg := range messages {
+		if done {
+			delete(m.sent, msg.ID)
+			continue
+		}
+
+		if len(succeeded) == 0 {
+			continue
+		}
+
+		if m.sent[msg.ID] == nil {
+			m.sent[msg.ID] = make(map[int]bool)
+		}
+
+		for _, publisher := range succeeded {
+			m.sent[msg.ID][publisher] = true
+		}
+	}
+}
+
+func (m *Multi) Close() error {
+	errs := make([]error, 0)
+
+	for _, publisher := range m.Publishers {
+		if err := publisher.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FromEnv sets up the publishers listed in PUBLISHERS, which defaults to
+// Redis alone. Redis is connected to through the given function, so that
+// it's only dialed when it's being published to. Listing webhook has data
+// matching registered webhooks delivered to them.
+func FromEnv(_db *gorm.DB, redis func() *data.RedisInfo) (data.Publisher, error) {
+	publishers := make([]data.Publisher, 0)
+
+	for _, name := range strings.Split(envOr("PUBLISHERS", "redis"), ",") {
+		var publisher data.Publisher
+		var err error
+
+		switch strings.TrimSpace(name) {
+		case "redis":
+			publisher, err = redisFromEnv(redis())
+		case "kafka":
+			publisher, err = kafkaFromEnv()
+		case "nats":
+			publisher, err = natsFromEnv()
+		case "webhook":
+			publisher = webhook.New(_db)
+		default:
+			err = fmt.Errorf("unknown publisher %q", name)
+		}
+
+		if err != nil {
+			NewMulti(publishers...).Close()
+			return nil, err
+		}
+
+		publishers = append(publishers, publisher)
+	}
+
+	if len(publishers) == 1 {
+		return publishers[0], nil
+	}
+
+	return NewMulti(publishers...), nil
+}
+
+func envOr(key string, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return fallback
+}
+
+func envBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("bad %s %q", key, v)
+	}
+
+	return b, nil
+}
+
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("bad %s %q", key, v)
+	}
+
+	return d, nil
+}
+
+// envPartitionKey reads how a publisher keys messages, defaulting to
+// PARTITION_KEY and then to the block number.
+func envPartitionKey(key string) (string, error) {
+	strategy := envOr(key, envOr("PARTITION_KEY", BlockNumberKey))
+	if strategy != BlockNumberKey && strategy != ContractKey {
+		return "", fmt.Errorf("bad %s %q, expected %q or %q", key, strategy, BlockNumberKey, ContractKey)
+	}
+
+	return strategy, nil
+}
@@ -0,0 +1,191 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/nats-io/nats.go"
+)
+
+func testMessages() []*data.Message {
+	return []*data.Message{
+		{
+			Topic:         data.BlockTopic,
+			ID:            "0xb1",
+			BlockNumber:   42,
+			Finality:      data.FinalityLatest,
+			Confirmations: 1,
+			Data:          &data.Block{Hash: "0xb1", Number: 42},
+		},
+		{
+			Topic:         data.EventTopic,
+			ID:            "0xb1-3-finalized",
+			BlockNumber:   42,
+			Contract:      "0xAbC0000000000000000000000000000000000001",
+			Finality:      data.FinalityFinalized,
+			Confirmations: 65,
+			Data:          &data.Event{Origin: "0xAbC0000000000000000000000000000000000001", Index: 3, BlockNumber: 42},
+		},
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	block, event := testMessages()[0], testMessages()[1]
+
+	cases := []struct {
+		strategy string
+		msg      *data.Message
+		expected string
+	}{
+		{BlockNumberKey, block, "42"},
+		{BlockNumberKey, event, "42"},
+		{ContractKey, block, "42"},
+		{ContractKey, event, "0xabc0000000000000000000000000000000000001"},
+	}
+
+	for _, c := range cases {
+		if key := partitionKey(c.strategy, c.msg); key != c.expected {
+			t.Errorf("%s key of %s message is %q, expected %q", c.strategy, c.msg.Topic, key, c.expected)
+		}
+	}
+}
+
+func TestKafkaPublish(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, KafkaConfig("nyx", true))
+
+	expect := func(topic string, key string, id string) {
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			encoded, _ := msg.Key.Encode()
+
+			if msg.Topic != topic || string(encoded) != key {
+				return fmt.Errorf("sent to %s keyed by %s, expected %s keyed by %s", msg.Topic, encoded, topic, key)
+			}
+
+			headers := make(map[string]string)
+			for _, header := range msg.Headers {
+				headers[string(header.Key)] = string(header.Value)
+			}
+
+			if headers[KafkaMessageIDHeader] != id {
+				return fmt.Errorf("sent with message ID %q, expected %q", headers[KafkaMessageIDHeader], id)
+			}
+
+			if headers[KafkaFinalityHeader] == "" || headers[KafkaConfirmationsHeader] == "" {
+				return fmt.Errorf("sent without finality and confirmations headers: %v", headers)
+			}
+
+			return nil
+		})
+	}
+
+	expect("nyx.block", "42", "0xb1")
+	expect("nyx.event.finalized", "0xabc0000000000000000000000000000000000001", "0xb1-3-finalized")
+
+	kafka := NewKafka(producer, "nyx.", ContractKey)
+
+	if err := kafka.Publish(context.Background(), testMessages()...); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	if err := kafka.Close(); err != nil {
+		t.Fatalf("failed to close: %s", err)
+	}
+}
+
+// recordingJetStream stands in for JetStream, keeping what's published.
+type recordingJetStream struct {
+	published []*nats.Msg
+}
+
+func (r *recordingJetStream) PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	r.published = append(r.published, m)
+	return &nats.PubAck{Sequence: uint64(len(r.published))}, nil
+}
+
+func TestNATSPublish(t *testing.T) {
+	js := &recordingJetStream{}
+
+	if err := NewNATS(nil, js, "nyx.", BlockNumberKey, true).Publish(context.Background(), testMessages()...); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	expected := []struct {
+		subject       string
+		id            string
+		finality      string
+		confirmations string
+	}{
+		{"nyx.block.42", "0xb1", "latest", "1"},
+		{"nyx.event.finalized.42", "0xb1-3-finalized", "finalized", "65"},
+	}
+
+	if len(js.published) != len(expected) {
+		t.Fatalf("published %d messages, expected %d", len(js.published), len(expected))
+	}
+
+	for i, e := range expected {
+		msg := js.published[i]
+
+		if msg.Subject != e.subject {
+			t.Errorf("published on %s, expected %s", msg.Subject, e.subject)
+		}
+
+		if id := msg.Header.Get(nats.MsgIdHdr); id != e.id {
+			t.Errorf("published with message ID %q, expected %q", id, e.id)
+		}
+
+		if msg.Header.Get(NATSFinalityHeader) != e.finality || msg.Header.Get(NATSConfirmationsHeader) != e.confirmations {
+			t.Errorf("published %s as %q with %q confirmations, expected %q with %q", msg.Subject, msg.Header.Get(NATSFinalityHeader), msg.Header.Get(NATSConfirmationsHeader), e.finality, e.confirmations)
+		}
+
+		if len(msg.Data) == 0 {
+			t.Errorf("published %s without data", msg.Subject)
+		}
+	}
+}
+
+// flakyPublisher fails as many times as it's told to, then counts what it
+// publishes.
+type flakyPublisher struct {
+	failures  int
+	published int
+}
+
+func (f *flakyPublisher) Publish(ctx context.Context, messages ...*data.Message) error {
+	if f.failures > 0 {
+		f.failures--
+		return fmt.Errorf("unavailable")
+	}
+
+	f.published += len(messages)
+	return nil
+}
+
+func (f *flakyPublisher) Close() error {
+	return nil
+}
+
+func TestMultiRetriesOnlyFailedPublishers(t *testing.T) {
+	healthy, flaky := &flakyPublisher{}, &flakyPublisher{failures: 1}
+	multi := NewMulti(healthy, flaky)
+
+	if err := multi.Publish(context.Background(), testMessages()...); err == nil {
+		t.Fatal("failure of one publisher went unreported")
+	}
+
+	if err := multi.Publish(context.Background(), testMessages()...); err != nil {
+		t.Fatalf("retry failed: %s", err)
+	}
+
+	if healthy.published != 2 || flaky.published != 2 {
+		t.Fatalf("publishers sent %d and %d messages, expected 2 each", healthy.published, flaky.published)
+	}
+
+	if len(multi.sent) != 0 {
+		t.Fatalf("%d messages are still remembered once every publisher sent them", len(multi.sent))
+	}
+}
@@ -0,0 +1,129 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// Redis publishes to the Pub/Sub channels or streams the WebSocket API reads
+// from, named after the topics of RedisInfo, with the finality appended for
+// data of blocks past latest. Data is wrapped along with the finality and
+// confirmations of its block, as {"finality":..,"confirmations":..,"data":..}.
+//
+// Neither Pub/Sub nor Streams drop duplicates on their own, so with a
+// deduplication window set each message ID is remembered for that long, and
+// a message published again within it is skipped.
+type Redis struct {
+	Info                *data.RedisInfo
+	DeduplicationWindow time.Duration
+}
+
+func NewRedis(info *data.RedisInfo, window time.Duration) *Redis {
+	return &Redis{
+		Info:                info,
+		DeduplicationWindow: window,
+	}
+}
+
+func redisFromEnv(info *data.RedisInfo) (*Redis, error) {
+	window, err := envDuration("REDIS_DEDUPLICATION_WINDOW", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRedis(info, window), nil
+}
+
+func (r *Redis) Publish(ctx context.Context, messages ...*data.Message) error {
+	for _, msg := range messages {
+		topic := data.FinalityTopic(r.topicName(msg.Topic), msg.Finality)
+
+		payload, err := wrap(msg)
+		if err != nil {
+			return err
+		}
+
+		if r.DeduplicationWindow > 0 {
+			key := fmt.Sprintf("nyx:published:%s:%s", topic, msg.ID)
+
+			fresh, err := r.Info.Client.SetNX(ctx, key, 1, r.DeduplicationWindow).Result()
+			if err != nil {
+				return err
+			}
+
+			if !fresh {
+				continue
+			}
+
+			if err := r.send(ctx, topic, payload); err != nil {
+				// Forget about it, so that it's published when retried
+				r.Info.Client.Del(ctx, key)
+				return err
+			}
+
+			continue
+		}
+
+		if err := r.send(ctx, topic, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// wrap encodes the message's data along with its finality and confirmations.
+func wrap(msg *data.Message) (string, error) {
+	value, err := msg.Data.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	finality := msg.Finality
+	if finality == "" {
+		finality = data.FinalityLatest
+	}
+
+	return fmt.Sprintf(`{"finality":%q,"confirmations":%d,"data":%s}`, finality, msg.Confirmations, value), nil
+}
+
+// send publishes on a topic over the configured transport. Streams are
+// trimmed to roughly their maximum length as entries are added, which is
+// cheaper for Redis than trimming exactly.
+func (r *Redis) send(ctx context.Context, topic string, payload interface{}) error {
+	if !r.Info.IsStreams() {
+		return r.Info.Client.Publish(ctx, topic, payload).Err()
+	}
+
+	return r.Info.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: r.Info.StreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"data": payload},
+	}).Err()
+}
+
+func (r *Redis) topicName(topic string) string {
+	switch topic {
+	case data.BlockTopic:
+		return r.Info.BlockPublishTopic
+	case data.TransactionTopic:
+		return r.Info.TxPublishTopic
+	case data.EventTopic:
+		return r.Info.EventPublishTopic
+	case data.WithdrawalTopic:
+		return r.Info.WithdrawalPublishTopic
+	case data.ReorgTopic:
+		return r.Info.ReorgPublishTopic
+	}
+
+	return topic
+}
+
+func (r *Redis) Close() error {
+	return r.Info.Client.Close()
+}
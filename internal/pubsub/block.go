@@ -1,133 +1,62 @@
 package pubsub
 
 import (
-	"context"
 	"encoding/json"
-	"sync"
-	"time"
 
-	"github.com/go-redis/redis/v8"
-	"github.com/gorilla/websocket"
 	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
-	"gorm.io/gorm"
 )
 
 type BlockConsumer struct {
-	Client     *redis.Client
-	Requests   map[string]*SubscriptionRequest
-	Connection *websocket.Conn
-	Pubsub     *redis.PubSub
-	DB         *gorm.DB
-	ConnLock   *sync.Mutex
-	TopicLock  *sync.RWMutex
+	*topicConsumer
 }
 
-func (b *BlockConsumer) Subscribe() {
-	b.Pubsub = b.Client.Subscribe(context.Background(), "block")
-}
-
-func (b *BlockConsumer) Listen() {
-	for {
-		msg, err := b.Pubsub.ReceiveTimeout(context.Background(), time.Duration(1)*time.Second)
-		if err != nil {
-			continue
-		}
-
-		switch m := msg.(type) {
-		case *redis.Subscription:
-
-			if m.Kind == "unsubscribe" {
-				return
-			}
-
-			b.SendData(&SubscriptionResponse{
-				Code: 1,
-				Msg:  "Subscribed to block topic",
-			})
-
-		case *redis.Message:
-			b.Send(m.Payload)
-		}
-	}
-}
-
-func (b *BlockConsumer) Send(data string) {
-	var req *SubscriptionRequest
-
+func (b *BlockConsumer) Send(data string) bool {
 	b.TopicLock.RLock()
-
-	for _, r := range b.Requests {
-		req = r
-		break
-	}
-
+	matched := b.Requests.All()
 	b.TopicLock.RUnlock()
 
-	if req == nil {
-		return
+	if len(matched) == 0 {
+		return true
 	}
 
 	var block struct {
 		Hash                string  `json:"hash"`
 		Number              uint64  `json:"number"`
-		Time                uint64  `json:"time" `
-		ParentHash          string  `json:"parent_hash"`
+		Time                uint64  `json:"time"`
+		ParentHash          string  `json:"parentHash"`
 		Difficulty          string  `json:"difficulty"`
-		GasUsed             uint64  `json:"gas_used" `
-		GasLimit            uint64  `json:"gas_limit"`
+		GasUsed             uint64  `json:"gasUsed"`
+		GasLimit            uint64  `json:"gasLimit"`
 		Nonce               string  `json:"nonce"`
 		Miner               string  `json:"miner"`
 		Size                float64 `json:"size"`
-		StateRootHash       string  `json:"state_root_hash"`
-		UncleHash           string  `json:"uncle_hash"`
-		TransactionRootHash string  `json:"transaction_root_hash"`
-		ReceiptRootHash     string  `json:"receipt_root_hash"`
-		ExtraData           string  `json:"extra_data"`
+		StateRootHash       string  `json:"stateRootHash"`
+		UncleHash           string  `json:"uncleHash"`
+		TransactionRootHash string  `json:"txRootHash"`
+		ReceiptRootHash     string  `json:"receiptRootHash"`
+		ExtraData           string  `json:"extraData"`
+		BaseFee             string  `json:"baseFee"`
+		BlobGasUsed         uint64  `json:"blobGasUsed"`
+		ExcessBlobGas       uint64  `json:"excessBlobGas"`
+		WithdrawalsRoot     string  `json:"withdrawalsRoot"`
+		ParentBeaconRoot    string  `json:"parentBeaconRoot"`
 	}
 
-	msg := []byte(data)
+	published, ok := unwrap("block", data)
+	if !ok {
+		return true
+	}
 
-	err := json.Unmarshal(msg, &block)
+	err := json.Unmarshal(published.Data, &block)
 	if err != nil {
 		logger.S().Errorf("Failed to Decode Published block to JSON: %v", err.Error())
-		return
+		return true
 	}
 
-	b.SendData(&block)
-}
-
-func (b *BlockConsumer) SendData(data interface{}) bool {
-	b.ConnLock.Lock()
-	defer b.ConnLock.Unlock()
-
-	if err := b.Connection.WriteJSON(data); err != nil {
-		logger.S().Errorf("Failed to send block data over client: %v", err.Error())
-		return false
-	}
-	return true
-}
-
-func (b *BlockConsumer) Unsubscribe() {
-	if b.Pubsub == nil {
-		logger.S().Warn("Pubsub is nil, cannot unsubscribe")
-		return
+	matched = live(matched, block.Hash, published.Confirmations, &block)
+	if len(matched) == 0 {
+		return true
 	}
 
-	if err := b.Pubsub.Unsubscribe(context.Background(), "block"); err != nil {
-		logger.S().Errorf("Failed to unsubscribe from block topic: %v", err.Error())
-		return
-	}
-
-	resp := &SubscriptionResponse{
-		Code: 1,
-		Msg:  "Unsubscribed from block topic",
-	}
-
-	b.ConnLock.Lock()
-	defer b.ConnLock.Unlock()
-
-	if err := b.Connection.WriteJSON(resp); err != nil {
-		logger.S().Errorf("Failed to send unsubscribe confirmation over client: %v", err.Error())
-		return
-	}
+	return b.SendEnvelope(matched, published.Confirmations, &block)
 }
@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// topicConsumer is what consumers of every topic have in common: it reads
+// the topic's channel over Pub/Sub or Redis Streams, handing published data
+// over to the topic's Send, until it's unsubscribed or closed.
+type topicConsumer struct {
+	Client     *redis.Client
+	Requests   *SubscriptionIndex
+	Connection *websocket.Conn
+	Pubsub     *redis.PubSub
+	Stream     *Stream
+	DB         *gorm.DB
+	ConnLock   *sync.Mutex
+	TopicLock  *sync.RWMutex
+	Sequence   *uint64
+	Streams    *StreamConfig
+	Channel    string
+
+	topic     string
+	send      func(string) bool
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func newTopicConsumer(topic string, client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig) *topicConsumer {
+	return &topicConsumer{
+		Client:     client,
+		Requests:   requests,
+		Connection: conn,
+		DB:         db,
+		ConnLock:   connLock,
+		TopicLock:  topicLock,
+		Sequence:   sequence,
+		Streams:    streams,
+		Channel:    channel,
+		topic:      topic,
+		ready:      make(chan struct{}),
+	}
+}
+
+// start subscribes to the topic and listens on it in the background, handing
+// published data over to send.
+func (c *topicConsumer) start(send func(string) bool) {
+	c.send = send
+
+	c.Subscribe()
+	go c.Listen()
+}
+
+func (c *topicConsumer) Subscribe() {
+	if c.Streams != nil {
+		c.Stream = c.Streams.Open(c.Client, c.Channel)
+		return
+	}
+
+	c.Pubsub = c.Client.Subscribe(context.Background(), c.Channel)
+}
+
+func (c *topicConsumer) Listen() {
+	if c.Stream != nil {
+		if err := c.Stream.Subscribe(); err != nil {
+			logger.S().Errorf("Failed to subscribe to %s stream: %v", c.topic, err.Error())
+			c.SendData(&SubscriptionResponse{
+				Code: 0,
+				Msg:  fmt.Sprintf("Failed to subscribe to %s topic", c.topic),
+			})
+			return
+		}
+
+		c.subscribed()
+		c.Stream.Listen(c.send)
+		return
+	}
+
+	for {
+		msg, err := c.Pubsub.ReceiveTimeout(context.Background(), time.Duration(1)*time.Second)
+		if err != nil {
+			if isClosed(err) {
+				return
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+
+			if m.Kind == "unsubscribe" {
+				return
+			}
+
+			c.subscribed()
+
+		case *redis.Message:
+			c.send(m.Payload)
+		}
+	}
+}
+
+// subscribed lets the client know once the topic subscription is confirmed.
+func (c *topicConsumer) subscribed() {
+	c.readyOnce.Do(func() { close(c.ready) })
+
+	c.SendData(&SubscriptionResponse{
+		Code: 1,
+		Msg:  fmt.Sprintf("Subscribed to %s topic", c.topic),
+	})
+}
+
+// Ready is closed once the topic subscription is confirmed, after which no
+// published data is missed.
+func (c *topicConsumer) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// SendEnvelope sends data along with the subscriptions it matched.
+func (c *topicConsumer) SendEnvelope(matched []*SubscriptionRequest, confirmations uint64, data interface{}) bool {
+	return sendEnvelope(c.Connection, c.ConnLock, c.Sequence, c.topic, c.Stream, matched, confirmations, data)
+}
+
+func (c *topicConsumer) SendData(data interface{}) bool {
+	c.ConnLock.Lock()
+	defer c.ConnLock.Unlock()
+
+	if err := c.Connection.WriteJSON(data); err != nil {
+		logger.S().Errorf("Failed to send %s data over client: %v", c.topic, err.Error())
+		return false
+	}
+	return true
+}
+
+func (c *topicConsumer) Unsubscribe() {
+	if c.Stream != nil {
+		c.Stream.Close()
+		c.SendData(&SubscriptionResponse{
+			Code: 1,
+			Msg:  fmt.Sprintf("Unsubscribed from %s topic", c.topic),
+		})
+		return
+	}
+
+	if c.Pubsub == nil {
+		logger.S().Warnf("Pubsub is nil while unsubscribing from %s topic", c.topic)
+		return
+	}
+
+	if err := c.Pubsub.Unsubscribe(context.Background(), c.Channel); err != nil {
+		logger.S().Errorf("Failed to unsubscribe from %s topic: %v", c.topic, err.Error())
+		return
+	}
+
+	resp := &SubscriptionResponse{
+		Code: 1,
+		Msg:  fmt.Sprintf("Unsubscribed from %s topic", c.topic),
+	}
+
+	c.ConnLock.Lock()
+	defer c.ConnLock.Unlock()
+
+	if err := c.Connection.WriteJSON(resp); err != nil {
+		logger.S().Errorf("Failed to send unsubscription response over client: %v", err.Error())
+		return
+	}
+}
+
+func (c *topicConsumer) Close() {
+	if c.Stream != nil {
+		c.Stream.Close()
+		return
+	}
+
+	if c.Pubsub == nil {
+		return
+	}
+
+	if err := c.Pubsub.Close(); err != nil {
+		logger.S().Errorf("Failed to close %s topic subscription: %v", c.topic, err.Error())
+	}
+}
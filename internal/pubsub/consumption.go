@@ -1,65 +1,161 @@
 package pubsub
 
 import (
+	"encoding/json"
+	"errors"
+	"sort"
 	"sync"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 	"gorm.io/gorm"
 )
 
 type Consumer interface {
 	Subscribe()
 	Listen()
-	Send(data string)
+	// Send hands published data over to the client, reporting false only
+	// when writing to the connection failed
+	Send(data string) bool
 	SendData(data interface{}) bool
 	Unsubscribe()
+	Close()
+	Ready() <-chan struct{}
 }
 
-func NewBlockConsumer(client *redis.Client, requests map[string]*SubscriptionRequest, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex) *BlockConsumer {
-	consumer := BlockConsumer{
-		Client:     client,
-		Requests:   requests,
-		Connection: conn,
-		DB:         db,
-		ConnLock:   connLock,
-		TopicLock:  topicLock,
+// Envelope wraps data sent to a client with the topic it was published on
+// and the subscriptions it matched. Seq grows by one with every envelope
+// sent over a connection, so that clients can spot a missing one. Finality
+// is the phase the data's block had completed when it was sent, and
+// Confirmations how many blocks the chain had from it up to the head. ID
+// is the entry of Stream the data was read from, for resuming that stream.
+type Envelope struct {
+	Topic         string      `json:"topic"`
+	Stream        string      `json:"stream,omitempty"`
+	ID            string      `json:"id,omitempty"`
+	Subscriptions []string    `json:"subscriptions"`
+	Sequence      uint64      `json:"seq"`
+	Finality      string      `json:"finality"`
+	Confirmations uint64      `json:"confirmations"`
+	Data          interface{} `json:"data"`
+}
+
+// Published is how data is published to Redis, along with the finality
+// and confirmations of its block.
+type Published struct {
+	Finality      string          `json:"finality"`
+	Confirmations uint64          `json:"confirmations"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// unwrap decodes data published on a topic, logging it when it's malformed.
+func unwrap(topic string, payload string) (*Published, bool) {
+	var published Published
+
+	if err := json.Unmarshal([]byte(payload), &published); err != nil || len(published.Data) == 0 {
+		logger.S().Errorf("Failed to decode published %s: %v", topic, err)
+		return nil, false
+	}
+
+	return &published, true
+}
+
+// sendEnvelope numbers and writes an envelope, along with the stream entry
+// it came from when reading from Redis Streams. It's numbered while holding
+// the connection's lock, so that envelopes go out in sequence. Every
+// subscription matched shares the finality of the consumer which matched
+// them.
+func sendEnvelope(conn *websocket.Conn, connLock *sync.Mutex, sequence *uint64, topic string, stream *Stream, matched []*SubscriptionRequest, confirmations uint64, data interface{}) bool {
+	names := make([]string, 0, len(matched))
+	for _, req := range matched {
+		names = append(names, req.Key())
+	}
+
+	finality := d.FinalityLatest
+	if len(matched) > 0 {
+		finality = matched[0].GetFinality()
+	}
+
+	sort.Strings(names)
+
+	connLock.Lock()
+	defer connLock.Unlock()
+
+	*sequence++
+
+	if err := conn.WriteJSON(&Envelope{
+		Topic:         topic,
+		Stream:        stream.Name(),
+		ID:            stream.Current(),
+		Subscriptions: names,
+		Sequence:      *sequence,
+		Finality:      finality,
+		Confirmations: confirmations,
+		Data:          data,
+	}); err != nil {
+		logger.S().Errorf("Failed to send %s data over client: %v", topic, err.Error())
+		return false
+	}
+	return true
+}
+
+// isClosed tells whether a receive failed because the subscription was closed,
+// after which it'll never succeed again.
+func isClosed(err error) bool {
+	return errors.Is(err, redis.ErrClosed)
+}
+
+func NewBlockConsumer(client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig) *BlockConsumer {
+	consumer := &BlockConsumer{
+		topicConsumer: newTopicConsumer("block", client, requests, channel, conn, db, connLock, topicLock, sequence, streams),
+	}
+
+	consumer.start(consumer.Send)
+
+	return consumer
+}
+
+func NewTransactionConsumer(client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig, registry *registry.Registry) *TransactionConsumer {
+	consumer := &TransactionConsumer{
+		topicConsumer: newTopicConsumer("transaction", client, requests, channel, conn, db, connLock, topicLock, sequence, streams),
+		Registry:      registry,
+	}
+
+	consumer.start(consumer.Send)
+
+	return consumer
+}
+
+func NewEventConsumer(client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig, registry *registry.Registry) *EventConsumer {
+	consumer := &EventConsumer{
+		topicConsumer: newTopicConsumer("event", client, requests, channel, conn, db, connLock, topicLock, sequence, streams),
+		Registry:      registry,
 	}
 
-	consumer.Subscribe()
-	go consumer.Listen()
+	consumer.start(consumer.Send)
 
-	return &consumer
+	return consumer
 }
 
-func NewTransactionConsumer(client *redis.Client, requests map[string]*SubscriptionRequest, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex) *TransactionConsumer {
-	consumer := TransactionConsumer{
-		Client:     client,
-		Requests:   requests,
-		Connection: conn,
-		DB:         db,
-		ConnLock:   connLock,
-		TopicLock:  topicLock,
+func NewReorgConsumer(client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig) *ReorgConsumer {
+	consumer := &ReorgConsumer{
+		topicConsumer: newTopicConsumer("reorg", client, requests, channel, conn, db, connLock, topicLock, sequence, streams),
 	}
 
-	consumer.Subscribe()
-	go consumer.Listen()
+	consumer.start(consumer.Send)
 
-	return &consumer
+	return consumer
 }
 
-func NewEventConsumer(client *redis.Client, requests map[string]*SubscriptionRequest, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex) *EventConsumer {
-	consumer := EventConsumer{
-		Client:     client,
-		Requests:   requests,
-		Connection: conn,
-		DB:         db,
-		ConnLock:   connLock,
-		TopicLock:  topicLock,
+func NewWithdrawalConsumer(client *redis.Client, requests *SubscriptionIndex, channel string, conn *websocket.Conn, db *gorm.DB, connLock *sync.Mutex, topicLock *sync.RWMutex, sequence *uint64, streams *StreamConfig) *WithdrawalConsumer {
+	consumer := &WithdrawalConsumer{
+		topicConsumer: newTopicConsumer("withdrawal", client, requests, channel, conn, db, connLock, topicLock, sequence, streams),
 	}
 
-	consumer.Subscribe()
-	go consumer.Listen()
+	consumer.start(consumer.Send)
 
-	return &consumer
+	return consumer
 }
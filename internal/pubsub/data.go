@@ -6,70 +6,143 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/websocket"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 	"gorm.io/gorm"
 )
 
+// SubscriptionManager keeps the subscriptions of a client, with a consumer
+// for each channel, i.e. topic and finality, they're on.
 type SubscriptionManager struct {
-	Topics     map[string]map[string]*SubscriptionRequest
+	Topics     map[string]*SubscriptionIndex
 	Consumers  map[string]Consumer
 	Client     *redis.Client
 	Connection *websocket.Conn
 	DB         *gorm.DB
 	ConnLock   *sync.Mutex
 	TopicLock  *sync.RWMutex
+	Sequence   *uint64
+	Registry   *registry.Registry
+	// Streams is set when topics are read from Redis Streams
+	Streams *StreamConfig
+}
+
+func NewSubscriptionManager(client *redis.Client, conn *websocket.Conn, db *gorm.DB, registry *registry.Registry, streams *StreamConfig) *SubscriptionManager {
+	return &SubscriptionManager{
+		Topics:     make(map[string]*SubscriptionIndex),
+		Consumers:  make(map[string]Consumer),
+		Client:     client,
+		Connection: conn,
+		DB:         db,
+		ConnLock:   &sync.Mutex{},
+		TopicLock:  &sync.RWMutex{},
+		Sequence:   new(uint64),
+		Registry:   registry,
+		Streams:    streams,
+	}
 }
 
 func (s *SubscriptionManager) Subscribe(req *SubscriptionRequest) {
+	if err := s.checkReplay(req); err != nil {
+		s.SendData(&SubscriptionResponse{
+			Code: 0,
+			Msg:  fmt.Sprintf("Bad subscription: %s", err.Error()),
+		})
+		return
+	}
+
 	s.TopicLock.Lock()
 	defer s.TopicLock.Unlock()
 
-	_, ok := s.Topics[req.Topic()]
+	_, ok := s.Topics[req.Channel()]
 
 	if !ok {
-		tmp := make(map[string]*SubscriptionRequest)
-		tmp[req.Name] = req
-		s.Topics[req.Topic()] = tmp
+		index := NewSubscriptionIndex()
+		index.Add(req)
+		s.Topics[req.Channel()] = index
 
 		switch req.Topic() {
 		case "block":
-			s.Consumers[req.Topic()] = NewBlockConsumer(s.Client, s.Connection, s.DB, s.ConnLock, s.TopicLock)
+			s.Consumers[req.Channel()] = NewBlockConsumer(s.Client, s.Topics[req.Channel()], req.Channel(), s.Connection, s.DB, s.ConnLock, s.TopicLock, s.Sequence, s.Streams)
 		case "transaction":
-			s.Consumers[req.Topic()] = NewTransactionConsumer(s.Client, s.Connection, s.DB, s.ConnLock, s.TopicLock)
+			s.Consumers[req.Channel()] = NewTransactionConsumer(s.Client, s.Topics[req.Channel()], req.Channel(), s.Connection, s.DB, s.ConnLock, s.TopicLock, s.Sequence, s.Streams, s.Registry)
 		case "event":
-			s.Consumers[req.Topic()] = NewEventConsumer(s.Client, s.Connection, s.DB, s.ConnLock, s.TopicLock)
+			s.Consumers[req.Channel()] = NewEventConsumer(s.Client, s.Topics[req.Channel()], req.Channel(), s.Connection, s.DB, s.ConnLock, s.TopicLock, s.Sequence, s.Streams, s.Registry)
+		case "withdrawal":
+			s.Consumers[req.Channel()] = NewWithdrawalConsumer(s.Client, s.Topics[req.Channel()], req.Channel(), s.Connection, s.DB, s.ConnLock, s.TopicLock, s.Sequence, s.Streams)
+		case "reorg":
+			s.Consumers[req.Channel()] = NewReorgConsumer(s.Client, s.Topics[req.Channel()], req.Channel(), s.Connection, s.DB, s.ConnLock, s.TopicLock, s.Sequence, s.Streams)
 		}
 
+		s.startReplay(req)
 		return
 	}
 
-	s.Topics[req.Topic()][req.Name] = req
-	s.Consumers[req.Topic()].SendData(&SubscriptionResponse{
+	stopReplay(s.Topics[req.Channel()], req.Key())
+
+	s.Topics[req.Channel()].Add(req)
+	s.Consumers[req.Channel()].SendData(&SubscriptionResponse{
 		Code: 1,
 		Msg:  fmt.Sprintf("Subscribed to %s topic", req.Topic()),
 	})
+
+	s.startReplay(req)
 }
 
 func (s *SubscriptionManager) Unsubscribe(req *SubscriptionRequest) {
 	s.TopicLock.Lock()
 	defer s.TopicLock.Unlock()
 
-	_, ok := s.Topics[req.Topic()]
+	_, ok := s.Topics[req.Channel()]
 
 	if !ok {
 		return
 	}
 
-	delete(s.Topics[req.Topic()], req.Name)
+	stopReplay(s.Topics[req.Channel()], req.Key())
+	s.Topics[req.Channel()].Remove(req.Key())
 
-	if len(s.Topics[req.Topic()]) > 0 {
-		s.Consumers[req.Topic()].SendData(&SubscriptionResponse{
+	if s.Topics[req.Channel()].Len() > 0 {
+		s.Consumers[req.Channel()].SendData(&SubscriptionResponse{
 			Code: 1,
 			Msg:  fmt.Sprintf("Unsubscribed from %s topic", req.Topic()),
 		})
 		return
 	}
 
-	s.Consumers[req.Topic()].Unsubscribe()
-	delete(s.Consumers, req.Topic())
-	delete(s.Topics, req.Topic())
+	s.Consumers[req.Channel()].Unsubscribe()
+	delete(s.Consumers, req.Channel())
+	delete(s.Topics, req.Channel())
+}
+
+// SendData writes a message to the client which doesn't belong to any
+// particular consumer, e.g. the response to a malformed request.
+func (s *SubscriptionManager) SendData(data interface{}) bool {
+	s.ConnLock.Lock()
+	defer s.ConnLock.Unlock()
+
+	if err := s.Connection.WriteJSON(data); err != nil {
+		logger.S().Errorf("Failed to send data over client: %v", err.Error())
+		return false
+	}
+	return true
+}
+
+// Close tears down every consumer of the client, once its connection is gone.
+func (s *SubscriptionManager) Close() {
+	s.TopicLock.Lock()
+	defer s.TopicLock.Unlock()
+
+	for topic, consumer := range s.Consumers {
+		consumer.Close()
+		delete(s.Consumers, topic)
+	}
+
+	for topic, index := range s.Topics {
+		for key := range index.Requests {
+			stopReplay(index, key)
+		}
+
+		delete(s.Topics, topic)
+	}
 }
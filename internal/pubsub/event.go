@@ -1,76 +1,41 @@
 package pubsub
 
 import (
-	"context"
 	"encoding/hex"
 	"encoding/json"
-	"sync"
-	"time"
 
-	"github.com/go-redis/redis/v8"
-	"github.com/gorilla/websocket"
 	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
 	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 	"github.com/lib/pq"
-	"gorm.io/gorm"
 )
 
 type EventConsumer struct {
-	Client     *redis.Client
-	Requests   map[string]*SubscriptionRequest
-	Connection *websocket.Conn
-	Pubsub     *redis.PubSub
-	DB         *gorm.DB
-	ConnLock   *sync.Mutex
-	TopicLock  *sync.RWMutex
-}
-
-func (e *EventConsumer) Subscribe() {
-	e.Pubsub = e.Client.Subscribe(context.Background(), "event")
-}
-
-func (e *EventConsumer) Listen() {
-	for {
-		msg, err := e.Pubsub.ReceiveTimeout(context.Background(), time.Duration(1)*time.Second)
-		if err != nil {
-			continue
-		}
-
-		switch m := msg.(type) {
-		case *redis.Subscription:
-
-			if m.Kind == "unsubscribe" {
-				return
-			}
+	*topicConsumer
 
-			e.SendData(&SubscriptionResponse{
-				Code: 1,
-				Msg:  "Subscribed to event topic",
-			})
-
-		case *redis.Message:
-			e.Send(m.Payload)
-		}
-	}
+	Registry *registry.Registry
 }
 
-func (e *EventConsumer) Send(msg string) {
+func (e *EventConsumer) Send(msg string) bool {
 	var event struct {
 		Origin          string         `json:"origin"`
 		Index           uint           `json:"index"`
 		Topics          pq.StringArray `json:"topics"`
 		Data            string         `json:"data"`
-		TransactionHash string         `json:"transaction_hash"`
-		BlockHash       string         `json:"block_hash"`
-		BlockNumber     uint64         `json:"block_number"`
+		TransactionHash string         `json:"txHash"`
+		BlockHash       string         `json:"blockHash"`
+		BlockNumber     uint64         `json:"blockNumber"`
 		Timestamp       uint64         `json:"timestamp"`
 	}
 
-	_msg := []byte(data)
+	published, ok := unwrap("event", msg)
+	if !ok {
+		return true
+	}
 
-	if err := json.Unmarshal(msg, &event); err != nil {
+	if err := json.Unmarshal(published.Data, &event); err != nil {
 		logger.S().Errorf("Failed to Decode Published event to JSON: %v", err.Error())
-		return
+		return true
 	}
 
 	data := make([]byte, 0)
@@ -82,7 +47,7 @@ func (e *EventConsumer) Send(msg string) {
 
 	if err != nil {
 		logger.S().Errorf("Failed to Decode Published event data from hex: %v", err.Error())
-		return
+		return true
 	}
 
 	_event := &d.Event{
@@ -96,58 +61,20 @@ func (e *EventConsumer) Send(msg string) {
 		Timestamp:       event.Timestamp,
 	}
 
-	var req *SubscriptionRequest
-
 	e.TopicLock.RLock()
-
-	for _, r := range e.Requests {
-		if r.DoesMatchWithPublishedEvent(_event) {
-			req = r
-			break
-		}
-	}
-
+	matched := e.Requests.MatchEvent(_event)
 	e.TopicLock.RUnlock()
 
-	if req == nil {
-		return
-	}
-
-	e.SendData(&_event)
-}
-
-func (e *EventConsumer) SendData(data interface{}) bool {
-	e.ConnLock.Lock()
-	defer e.ConnLock.Unlock()
-
-	if err := e.Connection.WriteJSON(data); err != nil {
-		logger.S().Errorf("Failed to send event data over client: %v", err.Error())
-		return false
-	}
-	return true
-}
-
-func (e *EventConsumer) Unsubscribe() {
-	if e.Pubsub == nil {
-		logger.S().Warn("Pubsub is nil while unsubscribing from event topic")
-		return
+	if len(matched) == 0 {
+		return true
 	}
 
-	if err := e.Pubsub.Unsubscribe(context.Background(), "event"); err != nil {
-		logger.S().Errorf("Failed to unsubscribe from event topic: %v", err.Error())
-		return
-	}
+	_event.Decoded = e.Registry.DecodeEvent(_event)
 
-	resp := &SubscriptionResponse{
-		Code: 1,
-		Msg:  "Unsubscribed from event topic",
+	matched = live(matched, _event.BlockHash, published.Confirmations, _event)
+	if len(matched) == 0 {
+		return true
 	}
 
-	e.ConnLock.Lock()
-	defer e.ConnLock.Unlock()
-
-	if err := e.Connection.WriteJSON(resp); err != nil {
-		logger.S().Errorf("Failed to send unsubscription response over client: %v", err.Error())
-		return
-	}
+	return e.SendEnvelope(matched, published.Confirmations, _event)
 }
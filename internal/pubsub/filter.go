@@ -0,0 +1,284 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+)
+
+// Set holds the values a field may take, lower cased so that checksummed and
+// plain addresses compare equal. A nil Set stands for `*` and matches all.
+type Set map[string]struct{}
+
+func NewSet(values ...string) Set {
+	set := make(Set, len(values))
+
+	for _, value := range values {
+		set[strings.ToLower(value)] = struct{}{}
+	}
+
+	return set
+}
+
+func (s Set) Matches(value string) bool {
+	if s == nil {
+		return true
+	}
+
+	_, ok := s[strings.ToLower(value)]
+	return ok
+}
+
+// EventFilter matches events emitted by any of the addresses, with each
+// topic being any of the given ones.
+type EventFilter struct {
+	Address Set
+	Topics  [4]Set
+}
+
+func (f *EventFilter) Matches(event *data.Event) bool {
+	if !f.Address.Matches(event.Origin) {
+		return false
+	}
+
+	for i, topics := range f.Topics {
+		if topics == nil {
+			continue
+		}
+
+		if i >= len(event.Topics) || !topics.Matches(event.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TransactionFilter matches transactions sent from and to any of the given
+// addresses.
+type TransactionFilter struct {
+	From Set
+	To   Set
+}
+
+func (f *TransactionFilter) Matches(tx *data.Transaction) bool {
+	to := tx.To
+	if to == "" {
+		to = tx.ContractAddress
+	}
+
+	return f.From.Matches(tx.From) && f.To.Matches(to)
+}
+
+// WithdrawalFilter matches withdrawals credited to any of the addresses by
+// any of the validators.
+type WithdrawalFilter struct {
+	Address   Set
+	Validator Set
+}
+
+func (f *WithdrawalFilter) Matches(withdrawal *data.Withdrawal) bool {
+	return f.Address.Matches(withdrawal.Address) && f.Validator.Matches(strconv.FormatUint(withdrawal.ValidatorIndex, 10))
+}
+
+// StringList is a filter field given either as a single value or a list of
+// them, in the fashion of eth_getLogs.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(raw []byte) error {
+	if string(raw) == "null" {
+		*l = nil
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		*l = StringList{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+
+	*l = values
+	return nil
+}
+
+// NumberList is StringList for numeric fields such as validator indices.
+type NumberList []uint64
+
+func (l *NumberList) UnmarshalJSON(raw []byte) error {
+	if string(raw) == "null" {
+		*l = nil
+		return nil
+	}
+
+	var value uint64
+	if err := json.Unmarshal(raw, &value); err == nil {
+		*l = NumberList{value}
+		return nil
+	}
+
+	var values []uint64
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+
+	*l = values
+	return nil
+}
+
+// FilterObject is the structured alternative to filters spelled out in the
+// subscription name, e.g.
+//
+//	{"type": "subscribe", "name": "event", "filter": {"address": ["0xA", "0xB"], "topics": ["Transfer(address,address,uint256)", null, ["0xC", "0xD"]]}}
+type FilterObject struct {
+	Address   StringList   `json:"address,omitempty"`
+	Topics    []StringList `json:"topics,omitempty"`
+	From      StringList   `json:"from,omitempty"`
+	To        StringList   `json:"to,omitempty"`
+	Validator NumberList   `json:"validator,omitempty"`
+}
+
+// splitList breaks a `[a,b,c]` filter segment into its values. Commas inside
+// event signatures, e.g. `[Transfer(address,address,uint256),0x...]`, don't
+// separate values.
+func splitList(segment string) []string {
+	if !strings.HasPrefix(segment, "[") {
+		return []string{segment}
+	}
+
+	segment = strings.TrimSuffix(strings.TrimPrefix(segment, "["), "]")
+
+	values := make([]string, 0)
+	depth, start := 0, 0
+
+	for i, r := range segment {
+		switch r {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				values = append(values, segment[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(values, segment[start:])
+}
+
+// addressSet builds the set of addresses of a filter field, nil standing
+// for any address.
+func addressSet(values []string) (Set, error) {
+	if len(values) == 0 || (len(values) == 1 && (values[0] == "*" || values[0] == "")) {
+		return nil, nil
+	}
+
+	for _, value := range values {
+		if !util.IsValidAddress(value) {
+			return nil, fmt.Errorf("bad address %q", value)
+		}
+	}
+
+	return NewSet(values...), nil
+}
+
+// topicSet builds the set of topics of a filter field, resolving event
+// signatures and addresses into the topics they stand for.
+func topicSet(position int, values []string) (Set, error) {
+	if len(values) == 0 || (len(values) == 1 && (values[0] == "*" || values[0] == "")) {
+		return nil, nil
+	}
+
+	topics := make([]string, 0, len(values))
+
+	for _, value := range values {
+		topic, ok := c.NormalizeEventTopic(position, value)
+		if !ok {
+			return nil, fmt.Errorf("bad topic %q", value)
+		}
+
+		topics = append(topics, topic)
+	}
+
+	return NewSet(topics...), nil
+}
+
+func numberSet(values []string) (Set, error) {
+	if len(values) == 0 || (len(values) == 1 && (values[0] == "*" || values[0] == "")) {
+		return nil, nil
+	}
+
+	for _, value := range values {
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("bad number %q", value)
+		}
+	}
+
+	return NewSet(values...), nil
+}
+
+func (f *FilterObject) eventFilter() (*EventFilter, error) {
+	if len(f.Topics) > 4 {
+		return nil, errors.New("at most 4 topics can be filtered on")
+	}
+
+	address, err := addressSet(f.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &EventFilter{Address: address}
+
+	for i, topics := range f.Topics {
+		if filter.Topics[i], err = topicSet(i, topics); err != nil {
+			return nil, err
+		}
+	}
+
+	return filter, nil
+}
+
+func (f *FilterObject) transactionFilter() (*TransactionFilter, error) {
+	from, err := addressSet(f.From)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := addressSet(f.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionFilter{From: from, To: to}, nil
+}
+
+func (f *FilterObject) withdrawalFilter() (*WithdrawalFilter, error) {
+	address, err := addressSet(f.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	validators := make([]string, 0, len(f.Validator))
+	for _, validator := range f.Validator {
+		validators = append(validators, strconv.FormatUint(validator, 10))
+	}
+
+	validator, err := numberSet(validators)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WithdrawalFilter{Address: address, Validator: validator}, nil
+}
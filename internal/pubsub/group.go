@@ -0,0 +1,182 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// durableGroupPrefix starts the name of every consumer group issued to a
+// client for resuming, which nobody else can guess.
+const durableGroupPrefix = "nyx-durable-"
+
+// groupKey names the Redis key a durable group is leased by. Once it expires
+// the group is destroyed, along with whatever it still had pending.
+func groupKey(group string) string {
+	return "nyx:group:" + group
+}
+
+// GetGroupTTL returns how long a durable group is kept after it was last
+// read from, set through STREAM_GROUP_TTL.
+func GetGroupTTL() time.Duration {
+	if v := os.Getenv("STREAM_GROUP_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+
+	return time.Duration(24) * time.Hour
+}
+
+// IssueGroup creates the name of a durable group for a client, which it can
+// resume reading with for as long as the group's lease is renewed.
+func IssueGroup(ctx context.Context, client *redis.Client) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	group := durableGroupPrefix + hex.EncodeToString(buf)
+
+	if err := client.Set(ctx, groupKey(group), 1, GetGroupTTL()).Err(); err != nil {
+		return "", err
+	}
+
+	return group, nil
+}
+
+// RenewGroup extends the lease of a durable group, telling whether it was
+// issued and hasn't expired yet.
+func RenewGroup(ctx context.Context, client *redis.Client, group string) (bool, error) {
+	if !strings.HasPrefix(group, durableGroupPrefix) {
+		return false, nil
+	}
+
+	return client.Expire(ctx, groupKey(group), GetGroupTTL()).Result()
+}
+
+// attachTTL is how long a connection holds on to a durable group after it
+// last said it's still there.
+const attachTTL = time.Duration(30) * time.Second
+
+// attachKey names the Redis key telling which connection reads a durable
+// group.
+func attachKey(group string) string {
+	return "nyx:group:" + group + ":attached"
+}
+
+// detachScript only lets go of a group held by the given connection, as it
+// may have expired and been attached by another since.
+var detachScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AttachGroup claims a durable group for a connection until ctx is
+// cancelled, telling whether it could. Connections reading a group share
+// whatever it has pending, so only one can read it at a time.
+func AttachGroup(ctx context.Context, client *redis.Client, group string) (bool, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return false, err
+	}
+
+	holder := hex.EncodeToString(buf)
+
+	attached, err := client.SetNX(ctx, attachKey(group), holder, attachTTL).Result()
+	if err != nil || !attached {
+		return false, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(attachTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := detachScript.Run(context.Background(), client, []string{attachKey(group)}, holder).Err(); err != nil {
+					logger.S().Errorf("Failed to detach consumer group %s: %s", group, err.Error())
+				}
+				return
+
+			case <-ticker.C:
+				if err := client.Expire(ctx, attachKey(group), attachTTL).Err(); err != nil && ctx.Err() == nil {
+					logger.S().Errorf("Failed to keep consumer group %s attached: %s", group, err.Error())
+				}
+			}
+		}
+	}()
+
+	return true, nil
+}
+
+// streamTopics lists every stream consumers read from.
+func streamTopics() []string {
+	topics := []string{d.ReorgTopic}
+
+	for _, topic := range []string{d.BlockTopic, d.TransactionTopic, d.EventTopic, d.WithdrawalTopic} {
+		for _, finality := range d.Finalities {
+			topics = append(topics, d.FinalityTopic(topic, finality))
+		}
+	}
+
+	return topics
+}
+
+// ExpireGroups destroys the durable groups whose lease ran out, checking
+// every interval until ctx is cancelled.
+func ExpireGroups(ctx context.Context, client *redis.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expireGroups(ctx, client)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func expireGroups(ctx context.Context, client *redis.Client) {
+	log := logger.S()
+
+	for _, topic := range streamTopics() {
+		groups, err := client.XInfoGroups(ctx, topic).Result()
+		if err != nil {
+			// Nothing was ever published on the topic
+			continue
+		}
+
+		for _, group := range groups {
+			if !strings.HasPrefix(group.Name, durableGroupPrefix) {
+				continue
+			}
+
+			leased, err := client.Exists(ctx, groupKey(group.Name)).Result()
+			if err != nil || leased > 0 {
+				continue
+			}
+
+			if err := client.XGroupDestroy(ctx, topic, group.Name).Err(); err != nil {
+				log.Errorf("Failed to destroy expired consumer group %s of %s stream: %s", group.Name, topic, err.Error())
+				continue
+			}
+
+			log.Infof("Destroyed expired consumer group %s of %s stream", group.Name, topic)
+		}
+	}
+}
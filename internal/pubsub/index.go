@@ -0,0 +1,186 @@
+package pubsub
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// SubscriptionIndex holds the subscriptions of a topic, bucketed by the
+// values of the most selective field each one filters on: contract address
+// then topic0 for events, sender then recipient for transactions, recipient
+// then validator for withdrawals. Published data is then only checked
+// against subscriptions in the buckets of its own values, plus those which
+// don't filter on any of these fields, rather than against all of them.
+type SubscriptionIndex struct {
+	Requests map[string]*SubscriptionRequest
+
+	buckets  [2]map[string]map[string]*SubscriptionRequest
+	wildcard map[string]*SubscriptionRequest
+}
+
+func NewSubscriptionIndex() *SubscriptionIndex {
+	return &SubscriptionIndex{
+		Requests: make(map[string]*SubscriptionRequest),
+		buckets: [2]map[string]map[string]*SubscriptionRequest{
+			make(map[string]map[string]*SubscriptionRequest),
+			make(map[string]map[string]*SubscriptionRequest),
+		},
+		wildcard: make(map[string]*SubscriptionRequest),
+	}
+}
+
+// indexedSets returns the sets of the fields the subscription is bucketed
+// by, in order of preference.
+func (s *SubscriptionRequest) indexedSets() [2]Set {
+	switch s.Topic() {
+	case "event":
+		if filter := s.GetLogEventFilters(); filter != nil {
+			return [2]Set{filter.Address, filter.Topics[0]}
+		}
+
+	case "transaction":
+		if filter := s.GetTransactionFilters(); filter != nil {
+			return [2]Set{filter.From, filter.To}
+		}
+
+	case "withdrawal":
+		if filter := s.GetWithdrawalFilters(); filter != nil {
+			return [2]Set{filter.Address, filter.Validator}
+		}
+	}
+
+	return [2]Set{}
+}
+
+// Add indexes a subscription, replacing the one with the same key if any.
+func (i *SubscriptionIndex) Add(req *SubscriptionRequest) {
+	key := req.Key()
+
+	i.Remove(key)
+	i.Requests[key] = req
+
+	for b, set := range req.indexedSets() {
+		if set == nil {
+			continue
+		}
+
+		for value := range set {
+			if i.buckets[b][value] == nil {
+				i.buckets[b][value] = make(map[string]*SubscriptionRequest)
+			}
+
+			i.buckets[b][value][key] = req
+		}
+
+		return
+	}
+
+	i.wildcard[key] = req
+}
+
+func (i *SubscriptionIndex) Remove(key string) {
+	req, ok := i.Requests[key]
+	if !ok {
+		return
+	}
+
+	delete(i.Requests, key)
+	delete(i.wildcard, key)
+
+	for b, set := range req.indexedSets() {
+		if set == nil {
+			continue
+		}
+
+		for value := range set {
+			delete(i.buckets[b][value], key)
+
+			if len(i.buckets[b][value]) == 0 {
+				delete(i.buckets[b], value)
+			}
+		}
+
+		return
+	}
+}
+
+// All returns every subscription, which is what unfiltered topics such as
+// block match.
+func (i *SubscriptionIndex) All() []*SubscriptionRequest {
+	all := make([]*SubscriptionRequest, 0, len(i.Requests))
+	for _, req := range i.Requests {
+		all = append(all, req)
+	}
+
+	return all
+}
+
+func (i *SubscriptionIndex) Len() int {
+	return len(i.Requests)
+}
+
+// candidates calls fn with every subscription which may match data having
+// the given values of the indexed fields. Each subscription lives in a
+// single bucket, so none is visited twice.
+func (i *SubscriptionIndex) candidates(values [2]string, fn func(*SubscriptionRequest)) {
+	for b, value := range values {
+		for _, req := range i.buckets[b][strings.ToLower(value)] {
+			fn(req)
+		}
+	}
+
+	for _, req := range i.wildcard {
+		fn(req)
+	}
+}
+
+// MatchEvent returns subscriptions the event matches.
+func (i *SubscriptionIndex) MatchEvent(event *data.Event) []*SubscriptionRequest {
+	topic0 := ""
+	if len(event.Topics) != 0 {
+		topic0 = event.Topics[0]
+	}
+
+	matched := make([]*SubscriptionRequest, 0)
+
+	i.candidates([2]string{event.Origin, topic0}, func(req *SubscriptionRequest) {
+		if req.DoesMatchWithPublishedEventData(event) {
+			matched = append(matched, req)
+		}
+	})
+
+	return matched
+}
+
+// MatchTransaction returns subscriptions the transaction matches.
+func (i *SubscriptionIndex) MatchTransaction(tx *data.Transaction) []*SubscriptionRequest {
+	to := tx.To
+	if to == "" {
+		to = tx.ContractAddress
+	}
+
+	matched := make([]*SubscriptionRequest, 0)
+
+	i.candidates([2]string{tx.From, to}, func(req *SubscriptionRequest) {
+		if req.DoesMatchWithPublishedTransactionData(tx) {
+			matched = append(matched, req)
+		}
+	})
+
+	return matched
+}
+
+// MatchWithdrawal returns subscriptions the withdrawal matches.
+func (i *SubscriptionIndex) MatchWithdrawal(withdrawal *data.Withdrawal) []*SubscriptionRequest {
+	matched := make([]*SubscriptionRequest, 0)
+
+	i.candidates([2]string{withdrawal.Address, strconv.FormatUint(withdrawal.ValidatorIndex, 10)}, func(req *SubscriptionRequest) {
+		if req.DoesMatchWithPublishedWithdrawalData(withdrawal) {
+			matched = append(matched, req)
+		}
+	})
+
+	return matched
+}
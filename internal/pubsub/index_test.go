@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+const transferSignature = "Transfer(address,address,uint256)"
+
+func testAddress(n uint64) string {
+	return common.BigToAddress(new(big.Int).SetUint64(n)).Hex()
+}
+
+// newEventIndex indexes n event subscriptions, most of them on a contract of
+// their own, some on topic0 only and a few on everything.
+func newEventIndex(tb testing.TB, n int) (*SubscriptionIndex, []*SubscriptionRequest) {
+	index := NewSubscriptionIndex()
+	requests := make([]*SubscriptionRequest, 0, n)
+
+	for i := 0; i < n; i++ {
+		var name string
+
+		switch {
+		case i%1000 == 0:
+			name = "event"
+		case i%100 == 0:
+			name = fmt.Sprintf("event/*/%s", common.BigToHash(big.NewInt(int64(i))).Hex())
+		default:
+			name = fmt.Sprintf("event/%s/%s", testAddress(uint64(i)), transferSignature)
+		}
+
+		req := &SubscriptionRequest{Name: name, Type: "subscribe"}
+		if err := req.Parse(); err != nil {
+			tb.Fatalf("failed to parse %q: %s", name, err)
+		}
+
+		index.Add(req)
+		requests = append(requests, req)
+	}
+
+	return index, requests
+}
+
+func newTransferEvent(contract uint64) *data.Event {
+	return &data.Event{
+		Origin: testAddress(contract),
+		Topics: []string{
+			"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+			common.BytesToHash(common.HexToAddress("0xaa").Bytes()).Hex(),
+			common.BytesToHash(common.HexToAddress("0xbb").Bytes()).Hex(),
+		},
+	}
+}
+
+func TestSubscriptionIndexMatchesLinearScan(t *testing.T) {
+	index, requests := newEventIndex(t, 10000)
+
+	for _, contract := range []uint64{0, 1, 100, 1000, 4242, 9999, 20000} {
+		event := newTransferEvent(contract)
+
+		// Subscriptions sharing a key replace each other, so the ones
+		// indexed are the ones to scan
+		expected := 0
+		for _, req := range index.Requests {
+			if req.DoesMatchWithPublishedEventData(event) {
+				expected++
+			}
+		}
+
+		if matched := len(index.MatchEvent(event)); matched != expected {
+			t.Errorf("contract %d: index matched %d subscriptions, linear scan %d", contract, matched, expected)
+		}
+	}
+
+	for _, req := range requests {
+		index.Remove(req.Key())
+	}
+
+	if index.Len() != 0 || len(index.wildcard) != 0 || len(index.buckets[0]) != 0 || len(index.buckets[1]) != 0 {
+		t.Errorf("index isn't empty after removing every subscription")
+	}
+}
+
+func BenchmarkSubscriptionIndexMatchEvent(b *testing.B) {
+	index, _ := newEventIndex(b, 10000)
+	event := newTransferEvent(4242)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index.MatchEvent(event)
+	}
+}
+
+func BenchmarkLinearScanMatchEvent(b *testing.B) {
+	_, requests := newEventIndex(b, 10000)
+	event := newTransferEvent(4242)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, req := range requests {
+			req.DoesMatchWithPublishedEventData(event)
+		}
+	}
+}
+
+func BenchmarkSubscriptionIndexMatchTransaction(b *testing.B) {
+	index := NewSubscriptionIndex()
+
+	for i := 0; i < 10000; i++ {
+		req := &SubscriptionRequest{Name: fmt.Sprintf("transaction/*/%s", testAddress(uint64(i)))}
+		if err := req.Parse(); err != nil {
+			b.Fatalf("failed to parse %q: %s", req.Name, err)
+		}
+
+		index.Add(req)
+	}
+
+	tx := &data.Transaction{
+		From: common.HexToAddress("0xaa").Hex(),
+		To:   testAddress(4242),
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		index.MatchTransaction(tx)
+	}
+}
@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"encoding/json"
+
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// ReorgConsumer lets clients know about chain reorganizations, so that they
+// can retract blocks, transactions and events they've already received from
+// the orphaned heights.
+type ReorgConsumer struct {
+	*topicConsumer
+}
+
+func (r *ReorgConsumer) Send(data string) bool {
+	r.TopicLock.RLock()
+	matched := r.Requests.All()
+	r.TopicLock.RUnlock()
+
+	if len(matched) == 0 {
+		return true
+	}
+
+	var reorg d.Reorg
+
+	published, ok := unwrap("reorg", data)
+	if !ok {
+		return true
+	}
+
+	if err := json.Unmarshal(published.Data, &reorg); err != nil {
+		logger.S().Errorf("Failed to Decode Published reorg to JSON: %v", err.Error())
+		return true
+	}
+
+	return r.SendEnvelope(matched, published.Confirmations, &reorg)
+}
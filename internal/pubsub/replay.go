@@ -0,0 +1,324 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/queue"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// replayPageSize is how many blocks worth of stored data are read at once
+// while replaying.
+const replayPageSize = 50
+
+// replay tracks a subscription which asked for data from a past block. Live
+// data it matches is held back until stored data has been streamed up to
+// the chain head, and is then only sent if its block wasn't part of the
+// replay. Blocks are told apart by hash rather than number, as they're
+// stored out of order: one missing from the replay is still sent live.
+type replay struct {
+	lock     sync.Mutex
+	done     bool
+	stopped  bool
+	replayed map[string]bool
+	buffered []*heldData
+
+	// policy decides up to which block confirmed data is replayed
+	policy queue.ConfirmationPolicy
+}
+
+type heldData struct {
+	hash          string
+	confirmations uint64
+	data          interface{}
+}
+
+// hold buffers live data matched by a subscription which is still being
+// replayed, or drops it when its block was replayed already. It reports
+// false when the data is to be sent right away.
+func (r *replay) hold(hash string, confirmations uint64, data interface{}) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.replayed[hash] {
+		return true
+	}
+
+	if r.done {
+		return false
+	}
+
+	r.buffered = append(r.buffered, &heldData{hash: hash, confirmations: confirmations, data: data})
+	return true
+}
+
+// sent records that data of the block with the given hash was replayed.
+func (r *replay) sent(hash string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.replayed[hash] = true
+}
+
+func (r *replay) stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stopped = true
+}
+
+func (r *replay) isStopped() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.stopped
+}
+
+// live returns those of the matched subscriptions data can be sent to right
+// away, holding it back for the ones still being replayed.
+func live(matched []*SubscriptionRequest, hash string, confirmations uint64, data interface{}) []*SubscriptionRequest {
+	ready := make([]*SubscriptionRequest, 0, len(matched))
+
+	for _, req := range matched {
+		if req.replay != nil && req.replay.hold(hash, confirmations, data) {
+			continue
+		}
+
+		ready = append(ready, req)
+	}
+
+	return ready
+}
+
+func getMaxReplayRange() uint64 {
+	if v := os.Getenv("MAX_REPLAY_RANGE"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			return limit
+		}
+	}
+
+	return 10000
+}
+
+// checkReplay validates the block a subscription wants data from, and sets
+// it up to hold live data back until its replay is over.
+func (s *SubscriptionManager) checkReplay(req *SubscriptionRequest) error {
+	if req.FromBlock == nil {
+		return nil
+	}
+
+	if req.Topic() == "reorg" {
+		return errors.New("reorgs aren't stored, so can't be replayed")
+	}
+
+	// Which blocks are safe or finalized is only known by the indexer
+	switch req.GetFinality() {
+	case d.FinalitySafe, d.FinalityFinalized:
+		return fmt.Errorf("%s data can't be replayed", req.GetFinality())
+	}
+
+	r := &replay{replayed: make(map[string]bool)}
+
+	// Confirmations are counted from stored blocks, as the node's safe and
+	// finalized heads aren't known here
+	if req.GetFinality() == d.FinalityConfirmed {
+		policy, err := queue.PolicyFromEnv()
+		if err != nil {
+			return err
+		}
+
+		if _, ok := policy.(queue.FixedDepth); !ok {
+			return fmt.Errorf("confirmed data can't be replayed with confirmations by %s", policy)
+		}
+
+		r.policy = policy
+	}
+
+	head := db.GetCurrentBlockNumber(s.DB)
+
+	if *req.FromBlock <= head && head-*req.FromBlock > getMaxReplayRange() {
+		return fmt.Errorf("can't replay more than %d blocks", getMaxReplayRange())
+	}
+
+	req.replay = r
+	return nil
+}
+
+// startReplay kicks off replay of a subscription which asked for it, once
+// its consumer is in place.
+func (s *SubscriptionManager) startReplay(req *SubscriptionRequest) {
+	if req.replay == nil {
+		return
+	}
+
+	go s.Replay(req, s.Consumers[req.Channel()])
+}
+
+// replayHead returns the block stored data is replayed up to, which for
+// confirmed data is the last block the confirmation policy considers
+// confirmed.
+func (s *SubscriptionManager) replayHead(req *SubscriptionRequest) (uint64, bool) {
+	head := db.GetCurrentBlockNumber(s.DB)
+
+	if req.replay.policy == nil {
+		return head, true
+	}
+
+	return req.replay.policy.ConfirmedHead(queue.Heads{Latest: head})
+}
+
+// stopReplay cancels the replay of the subscription with the given key, if
+// one is in progress.
+func stopReplay(index *SubscriptionIndex, key string) {
+	if req, ok := index.Requests[key]; ok && req.replay != nil {
+		req.replay.stop()
+	}
+}
+
+// Replay streams stored data matching the subscription from the block it
+// asked for up to the chain head, then hands over to the live feed. Live
+// data published in the meantime was held back, and is sent once replay is
+// over unless its block was part of the replay already.
+func (s *SubscriptionManager) Replay(req *SubscriptionRequest, consumer Consumer) {
+	log := logger.S()
+
+	select {
+	case <-consumer.Ready():
+	case <-time.After(time.Duration(10) * time.Second):
+		log.Warnf("Replaying %s before its topic subscription was confirmed", req.Key())
+	}
+
+	from := *req.FromBlock
+
+	for !req.replay.isStopped() {
+		head, ok := s.replayHead(req)
+		if !ok || from > head {
+			break
+		}
+
+		to := from + replayPageSize - 1
+		if to > head {
+			to = head
+		}
+
+		if err := s.replayRange(req, from, to); err != nil {
+			log.Errorf("Failed to replay %s: %s", req.Key(), err.Error())
+			s.SendData(&SubscriptionResponse{
+				Code: 0,
+				Msg:  fmt.Sprintf("Failed to replay %s from block %d", req.Key(), from),
+			})
+			break
+		}
+
+		from = to + 1
+	}
+
+	r := req.replay
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.done = true
+
+	if r.stopped {
+		return
+	}
+
+	for _, held := range r.buffered {
+		if !r.replayed[held.hash] {
+			s.SendEnvelope(req.Topic(), []*SubscriptionRequest{req}, held.confirmations, held.data)
+		}
+	}
+
+	r.buffered = nil
+}
+
+// replayRange sends stored data of the block range which the subscription
+// matches.
+func (s *SubscriptionManager) replayRange(req *SubscriptionRequest, from uint64, to uint64) error {
+	matched := []*SubscriptionRequest{req}
+
+	// Confirmations are counted up to the stored chain head, which is as
+	// far as the indexer has got
+	head := db.GetCurrentBlockNumber(s.DB)
+	confirmations := func(number uint64) uint64 {
+		if head < number {
+			return 1
+		}
+
+		return head - number + 1
+	}
+
+	switch req.Topic() {
+	case "block":
+		blocks, err := db.GetBlocksByNumberRange(s.DB, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, block := range blocks.Blocks {
+			req.replay.sent(block.Hash)
+			s.SendEnvelope("block", matched, confirmations(block.Number), block)
+		}
+
+	case "transaction":
+		txs, err := db.GetTransactionsByBlockNumberRange(s.DB, "", "", from, to)
+		if err != nil {
+			return err
+		}
+
+		if err := db.AttachReceipts(s.DB, txs.Transactions); err != nil {
+			return err
+		}
+
+		for _, tx := range txs.Transactions {
+			if req.DoesMatchWithPublishedTransactionData(tx) {
+				tx.Decoded = s.Registry.DecodeTransaction(tx)
+				req.replay.sent(tx.BlockHash)
+				s.SendEnvelope("transaction", matched, confirmations(tx.BlockNumber), tx)
+			}
+		}
+
+	case "event":
+		events, err := db.GetEventsByBlockNumberRange(s.DB, "", map[uint8]string{}, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events.Events {
+			if req.DoesMatchWithPublishedEventData(event) {
+				event.Decoded = s.Registry.DecodeEvent(event)
+				req.replay.sent(event.BlockHash)
+				s.SendEnvelope("event", matched, confirmations(event.BlockNumber), event)
+			}
+		}
+
+	case "withdrawal":
+		withdrawals, err := db.GetWithdrawalsByBlockNumberRange(s.DB, "", nil, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, withdrawal := range withdrawals.Withdrawals {
+			if req.DoesMatchWithPublishedWithdrawalData(withdrawal) {
+				req.replay.sent(withdrawal.BlockHash)
+				s.SendEnvelope("withdrawal", matched, confirmations(withdrawal.BlockNumber), withdrawal)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SendEnvelope sends data replayed to a subscription, numbered in the same
+// sequence as live data.
+func (s *SubscriptionManager) SendEnvelope(topic string, matched []*SubscriptionRequest, confirmations uint64, data interface{}) bool {
+	return sendEnvelope(s.Connection, s.ConnLock, s.Sequence, topic, nil, matched, confirmations, data)
+}
@@ -0,0 +1,236 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// StreamConfig has consumers read topics from Redis Streams rather than
+// Pub/Sub. Every topic is read through a consumer group, so that entries
+// published while the client is slow are waiting for it instead of lost.
+//
+// A named group outlives the connection: reconnecting with the same name
+// resumes right after the last entry which was delivered, starting with
+// those delivered but never acknowledged. Names are issued by IssueGroup,
+// groups are read by a single connection at a time, as told by AttachGroup,
+// and destroyed once they haven't been read from for a while.
+// Without a name, the group belongs to the connection alone and is destroyed
+// along with it.
+type StreamConfig struct {
+	Group string
+	// From holds the ID of the last entry the client has seen on each
+	// stream, as IDs only mean something to the stream they came from, so
+	// that reading resumes after it. Streams missing from it only read new
+	// entries.
+	From map[string]string
+}
+
+// Stream reads a topic's Redis stream through a consumer group, handing each
+// entry over and acknowledging it once it's been sent, or deliberately
+// skipped. Entries which couldn't be sent stay pending, to be read again
+// when the group is resumed.
+type Stream struct {
+	Client  *redis.Client
+	Topic   string
+	Group   string
+	From    string
+	Durable bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lock    sync.Mutex
+	current string
+	renewed time.Time
+}
+
+// Open prepares reading the topic's stream through the configured group.
+func (c *StreamConfig) Open(client *redis.Client, topic string) *Stream {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream := &Stream{
+		Client:  client,
+		Topic:   topic,
+		Group:   c.Group,
+		From:    c.From[topic],
+		Durable: c.Group != "",
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	if !stream.Durable {
+		stream.Group = newGroupName()
+	}
+
+	return stream
+}
+
+func newGroupName() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		logger.S().Errorf("Failed to generate consumer group name: %s", err.Error())
+	}
+
+	return "nyx-" + hex.EncodeToString(buf)
+}
+
+// Subscribe creates the consumer group, along with the stream if nothing was
+// published on the topic yet. An existing group carries on from where it
+// stopped, unless asked to resume from a given entry.
+func (s *Stream) Subscribe() error {
+	from := s.From
+	if from == "" {
+		from = "$"
+	}
+
+	err := s.Client.XGroupCreateMkStream(s.ctx, s.Topic, s.Group, from).Err()
+	if err == nil {
+		return nil
+	}
+
+	if !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	if s.From == "" {
+		return nil
+	}
+
+	return s.Client.XGroupSetID(s.ctx, s.Topic, s.Group, s.From).Err()
+}
+
+// Listen hands entries over to send until the stream is closed, or until one
+// can't be sent, as the client is then gone. Entries the group delivered
+// before but which never got acknowledged go first.
+func (s *Stream) Listen(send func(string) bool) {
+	pending := true
+
+	for {
+		id := ">"
+		if pending {
+			id = "0"
+		}
+
+		// Entries pending for a consumer are only read back by that same
+		// consumer, so it's named after the group, which only a single
+		// connection can read at a time.
+		streams, err := s.Client.XReadGroup(s.ctx, &redis.XReadGroupArgs{
+			Group:    s.Group,
+			Consumer: s.Group,
+			Streams:  []string{s.Topic, id},
+			Count:    100,
+			Block:    time.Duration(1) * time.Second,
+		}).Result()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+
+			if err != redis.Nil {
+				logger.S().Errorf("Failed to read from %s stream: %s", s.Topic, err.Error())
+				time.Sleep(time.Duration(1) * time.Second)
+			}
+
+			continue
+		}
+
+		s.renew()
+
+		read := 0
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				read++
+
+				if !s.deliver(msg, send) {
+					return
+				}
+			}
+		}
+
+		if pending && read == 0 {
+			pending = false
+		}
+	}
+}
+
+// renew extends the lease of a durable group every now and then, while it's
+// being read from.
+func (s *Stream) renew() {
+	if !s.Durable || time.Since(s.renewed) < time.Duration(1)*time.Minute {
+		return
+	}
+
+	if _, err := RenewGroup(s.ctx, s.Client, s.Group); err != nil {
+		logger.S().Errorf("Failed to renew consumer group %s: %s", s.Group, err.Error())
+		return
+	}
+
+	s.renewed = time.Now()
+}
+
+// deliver sends an entry, acknowledging it unless sending failed.
+func (s *Stream) deliver(msg redis.XMessage, send func(string) bool) bool {
+	payload, ok := msg.Values["data"].(string)
+	if ok {
+		s.lock.Lock()
+		s.current = msg.ID
+		s.lock.Unlock()
+
+		if !send(payload) {
+			logger.S().Warnf("Leaving %s stream entry %s pending, as it couldn't be sent", s.Topic, msg.ID)
+			return false
+		}
+	} else {
+		logger.S().Warnf("Skipping %s stream entry %s without data", s.Topic, msg.ID)
+	}
+
+	if err := s.Client.XAck(context.Background(), s.Topic, s.Group, msg.ID).Err(); err != nil {
+		logger.S().Errorf("Failed to acknowledge %s stream entry %s: %s", s.Topic, msg.ID, err.Error())
+	}
+
+	return true
+}
+
+// Name returns the stream entries are read from, which the IDs returned by
+// Current belong to. It's empty when not reading from a stream.
+func (s *Stream) Name() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.Topic
+}
+
+// Current returns the ID of the entry being sent, which clients can resume
+// from later on. It's empty when not reading from a stream.
+func (s *Stream) Current() string {
+	if s == nil {
+		return ""
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.current
+}
+
+// Close stops reading, destroying the group unless it's meant to be resumed.
+func (s *Stream) Close() {
+	s.cancel()
+
+	if s.Durable {
+		return
+	}
+
+	if err := s.Client.XGroupDestroy(context.Background(), s.Topic, s.Group).Err(); err != nil {
+		logger.S().Errorf("Failed to destroy consumer group of %s stream: %s", s.Topic, err.Error())
+	}
+}
@@ -1,123 +1,239 @@
 package pubsub
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/kunalsinghdadhwal/nyx/internal/data"
-	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
 )
 
 type SubscriptionRequest struct {
-	Name string `json:"name"`
-	Type string `json:"type"`
+	ID     string        `json:"id,omitempty"`
+	Name   string        `json:"name"`
+	Type   string        `json:"type"`
+	Filter *FilterObject `json:"filter,omitempty"`
+
+	// FromBlock asks for stored data since the given block to be sent
+	// ahead of live data
+	FromBlock *uint64 `json:"fromBlock,omitempty"`
+
+	// Finality is the phase blocks must have completed before their data
+	// is sent, latest when not given
+	Finality string `json:"finality,omitempty"`
+
+	replay *replay
+
+	eventFilter       *EventFilter
+	transactionFilter *TransactionFilter
+	withdrawalFilter  *WithdrawalFilter
+}
+
+type SubscriptionResponse struct {
+	Code uint   `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// Building blocks of the subscription grammar. Every position accepts a
+// single value, a `[a,b,c]` list of them or `*`.
+const (
+	addressPattern   = `0x[a-zA-Z0-9]{40}`
+	hashPattern      = `0x[a-zA-Z0-9]{64}`
+	signaturePattern = `[a-zA-Z_$][a-zA-Z0-9_$]*\([a-zA-Z0-9,\[\]() ]*\)`
+)
+
+func listOf(value string) string {
+	return fmt.Sprintf(`%s|\[(?:%s)(?:,(?:%s))*\]|\*`, value, value, value)
 }
 
+// subscriptionPattern is the whole subscription grammar, compiled once
+var subscriptionPattern = regexp.MustCompile(fmt.Sprintf("^(block|reorg|(transaction(/(%s)(/(%s))?)?)|(event(/(%s)(/(%s)(/(%s)(/(%s)(/(%s))?)?)?)?)?)|(withdrawal(/(%s)(/(%s))?)?))$",
+	listOf(addressPattern), listOf(addressPattern),
+	listOf(addressPattern), listOf(hashPattern+"|"+signaturePattern), listOf(hashPattern+"|"+addressPattern), listOf(hashPattern+"|"+addressPattern), listOf(hashPattern+"|"+addressPattern),
+	listOf(addressPattern), listOf(`[0-9]+`)))
+
 func (s *SubscriptionRequest) GetRegex() *regexp.Regexp {
-	pattern, err := regexp.Compile("^(block|(transaction(/(0x[a-zA-Z0-9]{40}|\\*)(/(0x[a-zA-Z0-9]{40}|\\*))?)?)|(event(/(0x[a-zA-Z0-9]{40}|\\*)(/(0x[a-zA-Z0-9]{64}|\\*)(/(0x[a-zA-Z0-9]{64}|\\*)(/(0x[a-zA-Z0-9]{64}|\\*)(/(0x[a-zA-Z0-9]{64}|\\*))?)?)?)?)?))$")
-	if err != nil {
-		logger.S().Warnln("failed to compile subscription regex:", err)
-		return nil
-	}
-	return pattern
+	return subscriptionPattern
 }
 
 func (s *SubscriptionRequest) Topic() string {
-	if strings.HasPrefix(s.Type, "block") {
+	if strings.HasPrefix(s.Name, "block") {
 		return "block"
 	}
 
-	if strings.HasPrefix(s.Type, "transaction") {
+	if strings.HasPrefix(s.Name, "transaction") {
 		return "transaction"
 	}
 
-	if strings.HasPrefix(s.Type, "event") {
+	if strings.HasPrefix(s.Name, "event") {
 		return "event"
 	}
 
+	if strings.HasPrefix(s.Name, "reorg") {
+		return "reorg"
+	}
+
+	if strings.HasPrefix(s.Name, "withdrawal") {
+		return "withdrawal"
+	}
+
 	return ""
 }
 
-func (s *SubscriptionRequest) GetLogEventFilters() []string {
-	pattern := s.GetRegex()
-	if pattern == nil {
-		return nil
+// GetFinality returns the finality asked for, defaulting to latest.
+func (s *SubscriptionRequest) GetFinality() string {
+	if s.Finality == "" {
+		return data.FinalityLatest
 	}
 
-	matches := pattern.FindStringSubmatch(s.Type)
-	return []string{matches[9], matches[11], matches[13], matches[15], matches[17]}
+	return s.Finality
 }
 
-func (s *SubscriptionRequest) DoesMatchWithPublishedEventData(event *data.Event) bool {
-	matchTopicInEvent := func(topic string, x uint8) bool {
-		if !(int(x) < len(event.Topics)) {
-			return topic == "*" || topic == ""
-		}
+// Channel is the topic along with the finality asked for, which data of is
+// published on its own.
+func (s *SubscriptionRequest) Channel() string {
+	return data.FinalityTopic(s.Topic(), s.GetFinality())
+}
+
+// Key identifies the subscription among those of a client, and in the
+// envelopes of data it matched. Subscriptions using a filter object share
+// their name, so unless they're given an ID the filter is part of it, as is
+// the finality when it isn't latest.
+func (s *SubscriptionRequest) Key() string {
+	if s.ID != "" {
+		return s.ID
+	}
 
-		status := false
+	key := s.Name
 
-		switch topic {
-		case "*":
-			status = true
-		case "":
-			status = true
-		default:
-			status = CheckSimilarity(topic, event.Topics[x])
+	if s.Filter != nil {
+		if filter, err := json.Marshal(s.Filter); err == nil {
+			key += string(filter)
 		}
-		return status
 	}
 
-	filters := s.GetLogEventFilters()
-	if filters == nil {
-		return false
+	if s.GetFinality() != data.FinalityLatest {
+		key += "@" + s.GetFinality()
+	}
+
+	return key
+}
+
+// Parse validates the subscription and builds the filter published data is
+// matched against, so that it's only done once per subscription.
+func (s *SubscriptionRequest) Parse() error {
+	matches := s.GetRegex().FindStringSubmatch(s.Name)
+	if matches == nil {
+		return fmt.Errorf("bad topic %q", s.Name)
+	}
+
+	if s.Finality != "" && !data.IsFinality(s.Finality) {
+		return fmt.Errorf("bad finality %q", s.Finality)
+	}
+
+	// Orphaned blocks are only ever latest ones
+	if s.Topic() == "reorg" && s.GetFinality() != data.FinalityLatest {
+		return errors.New("reorgs are only published as latest")
+	}
+
+	filter := s.Filter
+
+	if filter != nil {
+		if s.Name != s.Topic() {
+			return errors.New("filter object can only be given along with a bare topic name")
+		}
+	} else {
+		filter = &FilterObject{
+			Address: splitList(matches[9]),
+			Topics:  []StringList{splitList(matches[11]), splitList(matches[13]), splitList(matches[15]), splitList(matches[17])},
+			From:    splitList(matches[4]),
+			To:      splitList(matches[6]),
+		}
+
+		if s.Topic() == "withdrawal" {
+			filter.Address = splitList(matches[20])
+		}
 	}
 
-	status := false
+	var err error
 
-	switch filters[0] {
-	case "*", "":
-		status = matchTopicInEvent(filters[1], 0) &&
-			matchTopicInEvent(filters[2], 1) &&
-			matchTopicInEvent(filters[3], 2) &&
-			matchTopicInEvent(filters[4], 3)
+	switch s.Topic() {
+	case "event":
+		s.eventFilter, err = filter.eventFilter()
 
-	default:
-		if CheckSimilarity(filters[0], event.Origin) {
-			status = matchTopicInEvent(filters[1], 0) &&
-				matchTopicInEvent(filters[2], 1) &&
-				matchTopicInEvent(filters[3], 2) &&
-				matchTopicInEvent(filters[4], 3)
+	case "transaction":
+		s.transactionFilter, err = filter.transactionFilter()
+
+	case "withdrawal":
+		s.withdrawalFilter, err = filter.withdrawalFilter()
+
+		// Validators given in the name aren't numbers yet
+		if err == nil && s.Filter == nil {
+			s.withdrawalFilter.Validator, err = numberSet(splitList(matches[22]))
 		}
 	}
-	return status
+
+	return err
 }
 
+func (s *SubscriptionRequest) GetLogEventFilters() *EventFilter {
+	if s.eventFilter == nil && s.Parse() != nil {
+		return nil
+	}
+
+	return s.eventFilter
+}
 
-func (s *SubscriptionRequest) GetTransactionFilters() []string {
-	pattern := s.GetRegex()
-	if pattern == nil {
+func (s *SubscriptionRequest) DoesMatchWithPublishedEventData(event *data.Event) bool {
+	filter := s.GetLogEventFilters()
+	if filter == nil {
+		return false
+	}
+
+	return filter.Matches(event)
+}
+
+func (s *SubscriptionRequest) GetTransactionFilters() *TransactionFilter {
+	if s.transactionFilter == nil && s.Parse() != nil {
 		return nil
 	}
 
-	matches := pattern.FindStringSubmatch(s.Name)
-	return []string{matches[4], matches[6]}
+	return s.transactionFilter
 }
 
-func CheckSimilarity(a string, b string) bool {
-	req, err := regexp.Compile(fmt.Sprintf("(?i)^(%s)$", a))
-	if err != nil {
-		logger.S().Warnln("failed to compile similarity regex:", err)
+// DoesMatchWithPublishedTransactionData checks the transaction's sender and
+// recipient against the subscription. Contract creations have no recipient,
+// so the address of the deployed contract is matched instead.
+func (s *SubscriptionRequest) DoesMatchWithPublishedTransactionData(tx *data.Transaction) bool {
+	filter := s.GetTransactionFilters()
+	if filter == nil {
 		return false
 	}
-	return req.MatchString(b)
+
+	return filter.Matches(tx)
 }
 
-func (s *SubscriptionRequest) IsValidTopic() bool {
-	pattern := s.GetRegex()
-	if pattern == nil {
+func (s *SubscriptionRequest) GetWithdrawalFilters() *WithdrawalFilter {
+	if s.withdrawalFilter == nil && s.Parse() != nil {
+		return nil
+	}
+
+	return s.withdrawalFilter
+}
+
+// DoesMatchWithPublishedWithdrawalData checks the withdrawal's recipient and
+// validator index against the subscription.
+func (s *SubscriptionRequest) DoesMatchWithPublishedWithdrawalData(withdrawal *data.Withdrawal) bool {
+	filter := s.GetWithdrawalFilters()
+	if filter == nil {
 		return false
 	}
 
-	return pattern.MatchString(s.Name)
+	return filter.Matches(withdrawal)
+}
+
+func (s *SubscriptionRequest) IsValidTopic() bool {
+	return s.Parse() == nil
 }
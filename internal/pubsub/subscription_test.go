@@ -0,0 +1,129 @@
+package pubsub
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+func TestParse(t *testing.T) {
+	a, b := testAddress(1), testAddress(2)
+	hash := crypto.Keccak256Hash([]byte(transferSignature)).Hex()
+
+	tests := []struct {
+		name    string
+		filter  *FilterObject
+		wantErr bool
+	}{
+		{name: "block"},
+		{name: "reorg"},
+		{name: "transaction"},
+		{name: "transaction/" + a + "/*"},
+		{name: "transaction/[" + a + "," + b + "]/" + b},
+		{name: "event/" + a},
+		{name: "event/*/" + transferSignature},
+		{name: "event/[" + a + "," + b + "]/[" + transferSignature + "," + hash + "]/" + a},
+		{name: "event/*/*/*/*/" + hash},
+		{name: "withdrawal/" + a + "/[1,2]"},
+		{name: "event", filter: &FilterObject{Address: StringList{a}, Topics: []StringList{{transferSignature}, nil, {a, b}}}},
+		{name: "withdrawal", filter: &FilterObject{Validator: NumberList{7}}},
+
+		// Malformed lists
+		{name: "event/[" + a + "," + b, wantErr: true},
+		{name: "event/" + a + "," + b, wantErr: true},
+		{name: "event/[]", wantErr: true},
+		{name: "withdrawal/*/[1,x]", wantErr: true},
+
+		// Empty list items
+		{name: "event/[" + a + ",," + b + "]", wantErr: true},
+		{name: "event/[" + a + ",]", wantErr: true},
+		{name: "event", filter: &FilterObject{Address: StringList{a, ""}}, wantErr: true},
+
+		// Whitespace
+		{name: " block", wantErr: true},
+		{name: "event/[" + a + ", " + b + "]", wantErr: true},
+		{name: "event/*/Transfer(address, address, uint256)"},
+
+		// Addresses of the right length which aren't hex
+		{name: "event/0x" + strings.Repeat("z", 40), wantErr: true},
+
+		// Signatures only stand for topic0
+		{name: "event/*/*/" + transferSignature, wantErr: true},
+		{name: "event", filter: &FilterObject{Topics: []StringList{nil, {transferSignature}}}, wantErr: true},
+
+		// Too many topics
+		{name: "event/*/*/*/*/*/" + hash, wantErr: true},
+		{name: "event", filter: &FilterObject{Topics: []StringList{nil, nil, nil, nil, {hash}}}, wantErr: true},
+
+		// Filter objects only go along with bare topic names
+		{name: "event/" + a, filter: &FilterObject{Address: StringList{b}}, wantErr: true},
+
+		{name: "blocks", wantErr: true},
+		{name: "reorg/" + a, wantErr: true},
+	}
+
+	for _, test := range tests {
+		req := &SubscriptionRequest{Name: test.name, Type: "subscribe", Filter: test.filter}
+
+		err := req.Parse()
+		if test.wantErr && err == nil {
+			t.Errorf("expected %q with filter %+v to be rejected", test.name, test.filter)
+		}
+
+		if !test.wantErr && err != nil {
+			t.Errorf("failed to parse %q with filter %+v: %s", test.name, test.filter, err)
+		}
+	}
+}
+
+func TestParseMatches(t *testing.T) {
+	a, b := testAddress(1), testAddress(2)
+	hash := crypto.Keccak256Hash([]byte(transferSignature)).Hex()
+
+	event := &data.Event{
+		Origin: strings.ToLower(a),
+		Topics: []string{hash, common.BytesToHash(common.HexToAddress(b).Bytes()).Hex()},
+	}
+
+	tests := []struct {
+		name   string
+		filter *FilterObject
+		want   bool
+	}{
+		// Checksummed, lower and upper cased addresses are all the same
+		{name: "event/" + a, want: true},
+		{name: "event/" + strings.ToLower(a), want: true},
+		{name: "event/0x" + strings.ToUpper(a[2:]), want: true},
+		{name: "event/" + b, want: false},
+
+		// topic0 given as the signature or its hash, in any case
+		{name: "event/*/" + transferSignature, want: true},
+		{name: "event/*/Transfer(address, address, uint256)", want: true},
+		{name: "event/*/" + hash, want: true},
+		{name: "event/*/0x" + strings.ToUpper(hash[2:]), want: true},
+		{name: "event/*/Approval(address,address,uint256)", want: false},
+
+		// Any of a list matches, addresses standing for padded topics
+		{name: "event/[" + b + "," + a + "]/*/" + b, want: true},
+		{name: "event/*/*/[" + a + "]", want: false},
+		{name: "event/*/*/*/" + hash, want: false},
+
+		{name: "event", filter: &FilterObject{Address: StringList{a}, Topics: []StringList{{transferSignature}, {a, b}}}, want: true},
+		{name: "event", filter: &FilterObject{Topics: []StringList{nil, {a}}}, want: false},
+	}
+
+	for _, test := range tests {
+		req := &SubscriptionRequest{Name: test.name, Type: "subscribe", Filter: test.filter}
+
+		if err := req.Parse(); err != nil {
+			t.Fatalf("failed to parse %q: %s", test.name, err)
+		}
+
+		if got := req.DoesMatchWithPublishedEventData(event); got != test.want {
+			t.Errorf("expected %q with filter %+v to match: %t, got %t", test.name, test.filter, test.want, got)
+		}
+	}
+}
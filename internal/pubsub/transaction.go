@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"github.com/lib/pq"
+)
+
+type TransactionConsumer struct {
+	*topicConsumer
+
+	Registry *registry.Registry
+}
+
+func (t *TransactionConsumer) Send(msg string) bool {
+	var tx struct {
+		Hash                 string          `json:"hash"`
+		From                 string          `json:"from"`
+		To                   string          `json:"to"`
+		ContractAddress      string          `json:"contract_address"`
+		Value                string          `json:"value"`
+		Data                 string          `json:"data"`
+		Gas                  uint64          `json:"gas"`
+		GasPrice             string          `json:"gasPrice"`
+		Cost                 string          `json:"cost"`
+		Nonce                uint64          `json:"nonce"`
+		State                uint64          `json:"state"`
+		BlockHash            string          `json:"blockHash"`
+		BlockNumber          uint64          `json:"blockNumber"`
+		Timestamp            uint64          `json:"timestamp"`
+		Type                 uint8           `json:"type"`
+		MaxFeePerGas         string          `json:"maxFeePerGas"`
+		MaxPriorityFeePerGas string          `json:"maxPriorityFeePerGas"`
+		AccessList           json.RawMessage `json:"accessList"`
+		BlobVersionedHashes  pq.StringArray  `json:"blobVersionedHashes"`
+		MaxFeePerBlobGas     string          `json:"maxFeePerBlobGas"`
+		Receipt              *struct {
+			TransactionHash   string `json:"txHash"`
+			TransactionIndex  uint   `json:"txIndex"`
+			Type              uint8  `json:"type"`
+			Status            uint64 `json:"status"`
+			CumulativeGasUsed uint64 `json:"cumulativeGasUsed"`
+			GasUsed           uint64 `json:"gasUsed"`
+			EffectiveGasPrice string `json:"effectiveGasPrice"`
+			Fee               string `json:"fee"`
+			BlobGasUsed       uint64 `json:"blobGasUsed"`
+			BlobGasPrice      string `json:"blobGasPrice"`
+			LogsBloom         string `json:"logsBloom"`
+			BlockHash         string `json:"blockHash"`
+			BlockNumber       uint64 `json:"blockNumber"`
+		} `json:"receipt"`
+	}
+
+	published, ok := unwrap("transaction", msg)
+	if !ok {
+		return true
+	}
+
+	if err := json.Unmarshal(published.Data, &tx); err != nil {
+		logger.S().Errorf("Failed to Decode Published transaction to JSON: %v", err.Error())
+		return true
+	}
+
+	data := make([]byte, 0)
+	var err error
+
+	if len(tx.Data) != 0 {
+		data, err = hex.DecodeString(tx.Data[2:])
+	}
+
+	if err != nil {
+		logger.S().Errorf("Failed to Decode Published transaction data from hex: %v", err.Error())
+		return true
+	}
+
+	_tx := &d.Transaction{
+		Hash:                 tx.Hash,
+		From:                 tx.From,
+		To:                   tx.To,
+		ContractAddress:      tx.ContractAddress,
+		Value:                tx.Value,
+		Data:                 data,
+		Gas:                  tx.Gas,
+		GasPrice:             tx.GasPrice,
+		Cost:                 tx.Cost,
+		Nonce:                tx.Nonce,
+		State:                tx.State,
+		BlockHash:            tx.BlockHash,
+		BlockNumber:          tx.BlockNumber,
+		Timestamp:            tx.Timestamp,
+		Type:                 tx.Type,
+		MaxFeePerGas:         tx.MaxFeePerGas,
+		MaxPriorityFeePerGas: tx.MaxPriorityFeePerGas,
+		BlobVersionedHashes:  tx.BlobVersionedHashes,
+		MaxFeePerBlobGas:     tx.MaxFeePerBlobGas,
+	}
+
+	if len(tx.AccessList) != 0 && string(tx.AccessList) != "null" {
+		_tx.AccessList = string(tx.AccessList)
+	}
+
+	if r := tx.Receipt; r != nil {
+		_tx.Receipt = &d.Receipt{
+			TransactionHash:   r.TransactionHash,
+			TransactionIndex:  r.TransactionIndex,
+			Type:              r.Type,
+			Status:            r.Status,
+			CumulativeGasUsed: r.CumulativeGasUsed,
+			GasUsed:           r.GasUsed,
+			EffectiveGasPrice: r.EffectiveGasPrice,
+			Fee:               r.Fee,
+			BlobGasUsed:       r.BlobGasUsed,
+			BlobGasPrice:      r.BlobGasPrice,
+			LogsBloom:         r.LogsBloom,
+			BlockHash:         r.BlockHash,
+			BlockNumber:       r.BlockNumber,
+		}
+	}
+
+	t.TopicLock.RLock()
+	matched := t.Requests.MatchTransaction(_tx)
+	t.TopicLock.RUnlock()
+
+	if len(matched) == 0 {
+		return true
+	}
+
+	_tx.Decoded = t.Registry.DecodeTransaction(_tx)
+
+	matched = live(matched, _tx.BlockHash, published.Confirmations, _tx)
+	if len(matched) == 0 {
+		return true
+	}
+
+	return t.SendEnvelope(matched, published.Confirmations, _tx)
+}
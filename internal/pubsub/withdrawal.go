@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"encoding/json"
+
+	d "github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+type WithdrawalConsumer struct {
+	*topicConsumer
+}
+
+func (w *WithdrawalConsumer) Send(msg string) bool {
+	var withdrawal struct {
+		Index          uint64 `json:"index"`
+		ValidatorIndex uint64 `json:"validatorIndex"`
+		Address        string `json:"address"`
+		Amount         uint64 `json:"amount"`
+		BlockHash      string `json:"blockHash"`
+		BlockNumber    uint64 `json:"blockNumber"`
+		Timestamp      uint64 `json:"timestamp"`
+	}
+
+	published, ok := unwrap("withdrawal", msg)
+	if !ok {
+		return true
+	}
+
+	if err := json.Unmarshal(published.Data, &withdrawal); err != nil {
+		logger.S().Errorf("Failed to Decode Published withdrawal to JSON: %v", err.Error())
+		return true
+	}
+
+	_withdrawal := &d.Withdrawal{
+		Index:          withdrawal.Index,
+		ValidatorIndex: withdrawal.ValidatorIndex,
+		Address:        withdrawal.Address,
+		Amount:         withdrawal.Amount,
+		BlockHash:      withdrawal.BlockHash,
+		BlockNumber:    withdrawal.BlockNumber,
+		Timestamp:      withdrawal.Timestamp,
+	}
+
+	w.TopicLock.RLock()
+	matched := w.Requests.MatchWithdrawal(_withdrawal)
+	w.TopicLock.RUnlock()
+
+	if len(matched) == 0 {
+		return true
+	}
+
+	matched = live(matched, _withdrawal.BlockHash, published.Confirmations, _withdrawal)
+	if len(matched) == 0 {
+		return true
+	}
+
+	return w.SendEnvelope(matched, published.Confirmations, _withdrawal)
+}
@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Heads are the chain heads a confirmation policy decides by, as last
+// reported by the node. Safe and finalized are zero until the node reports
+// them.
+type Heads struct {
+	Latest    uint64
+	Safe      uint64
+	Finalized uint64
+}
+
+// ConfirmationPolicy decides when blocks are confirmed, after which they're
+// processed a second time and published as confirmed.
+type ConfirmationPolicy interface {
+	// ConfirmedHead returns the highest confirmed block, or false when
+	// none is yet
+	ConfirmedHead(heads Heads) (uint64, bool)
+	String() string
+}
+
+// FixedDepth confirms blocks once Depth blocks are built on top of them.
+type FixedDepth struct {
+	Depth uint64
+}
+
+func (f FixedDepth) ConfirmedHead(heads Heads) (uint64, bool) {
+	if heads.Latest < f.Depth {
+		return 0, false
+	}
+
+	return heads.Latest - f.Depth, true
+}
+
+func (f FixedDepth) String() string {
+	return fmt.Sprintf("depth of %d blocks", f.Depth)
+}
+
+// SafeTag confirms blocks once the node reports them as safe, i.e. justified
+// by the consensus layer.
+type SafeTag struct{}
+
+func (SafeTag) ConfirmedHead(heads Heads) (uint64, bool) {
+	return heads.Safe, heads.Safe > 0
+}
+
+func (SafeTag) String() string {
+	return "safe tag"
+}
+
+// FinalizedTag confirms blocks once the node reports them as finalized,
+// after which the consensus layer won't revert them.
+type FinalizedTag struct{}
+
+func (FinalizedTag) ConfirmedHead(heads Heads) (uint64, bool) {
+	return heads.Finalized, heads.Finalized > 0
+}
+
+func (FinalizedTag) String() string {
+	return "finalized tag"
+}
+
+// PolicyFromEnv reads the policy named by CONFIRMATION_POLICY, which is one
+// of depth, safe or finalized. It defaults to a fixed depth of
+// BLOCK_CONFIRMATIONS blocks. Both tags are polled from the RPC endpoint, so
+// they need a post-merge chain.
+func PolicyFromEnv() (ConfirmationPolicy, error) {
+	switch policy := os.Getenv("CONFIRMATION_POLICY"); policy {
+	case "", "depth":
+		depth, err := GetBlockConfirmations()
+		if err != nil {
+			return nil, err
+		}
+
+		return FixedDepth{Depth: depth}, nil
+	case "safe":
+		return SafeTag{}, nil
+	case "finalized":
+		return FinalizedTag{}, nil
+	default:
+		return nil, fmt.Errorf("unknown confirmation policy %q, expected depth, safe or finalized", policy)
+	}
+}
+
+// GetBlockConfirmations returns how many blocks need to be built on top of
+// a block for it to be confirmed at a fixed depth.
+func GetBlockConfirmations() (uint64, error) {
+	v := os.Getenv("BLOCK_CONFIRMATIONS")
+	if v == "" {
+		return 0, nil
+	}
+
+	confirmations, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad BLOCK_CONFIRMATIONS %q", v)
+	}
+
+	return confirmations, nil
+}
@@ -0,0 +1,60 @@
+package queue
+
+import "testing"
+
+func TestConfirmationPolicies(t *testing.T) {
+	heads := Heads{Latest: 100, Safe: 68, Finalized: 36}
+
+	cases := []struct {
+		policy    ConfirmationPolicy
+		confirmed uint64
+	}{
+		{FixedDepth{Depth: 0}, 100},
+		{FixedDepth{Depth: 12}, 88},
+		{SafeTag{}, 68},
+		{FinalizedTag{}, 36},
+	}
+
+	for _, c := range cases {
+		q := New(0, c.policy)
+		q.LatestBlock, q.SafeBlock, q.FinalizedBlock = heads.Latest, heads.Safe, heads.Finalized
+
+		if !q.CanBeConfirmed(c.confirmed) {
+			t.Errorf("block %d isn't confirmed by %s", c.confirmed, c.policy)
+		}
+
+		if q.CanBeConfirmed(c.confirmed + 1) {
+			t.Errorf("block %d is confirmed by %s", c.confirmed+1, c.policy)
+		}
+	}
+}
+
+func TestConfirmationPoliciesBeforeHeadsAreKnown(t *testing.T) {
+	for _, policy := range []ConfirmationPolicy{FixedDepth{Depth: 12}, SafeTag{}, FinalizedTag{}} {
+		if _, ok := policy.ConfirmedHead(Heads{Latest: 10}); ok {
+			t.Errorf("%s confirms blocks before there are any to confirm", policy)
+		}
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("CONFIRMATION_POLICY", "")
+	t.Setenv("BLOCK_CONFIRMATIONS", "12")
+
+	policy, err := PolicyFromEnv()
+	if err != nil || policy != (FixedDepth{Depth: 12}) {
+		t.Fatalf("default policy is %v (%v), expected a depth of 12 blocks", policy, err)
+	}
+
+	t.Setenv("CONFIRMATION_POLICY", "finalized")
+
+	if policy, err := PolicyFromEnv(); err != nil || policy != (FinalizedTag{}) {
+		t.Fatalf("policy is %v (%v), expected the finalized tag", policy, err)
+	}
+
+	t.Setenv("CONFIRMATION_POLICY", "eventually")
+
+	if _, err := PolicyFromEnv(); err == nil {
+		t.Fatal("unknown policy was accepted")
+	}
+}
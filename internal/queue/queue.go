@@ -3,23 +3,29 @@ package queue
 import (
 	"context"
 	"math"
-	"os"
-	"strconv"
 	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
 )
 
 type Block struct {
 	UnconfirmedProgress bool
-	Published           bool
-	UnconfirmedDone     bool
-	ConfirmedProgress   bool
-	ConfirmedDone       bool
-	LastAttempted       time.Time
-	Delay               time.Duration
+	// Published tells which finalities the block was published with
+	Published         map[string]bool
+	FinalityProgress  bool
+	UnconfirmedDone   bool
+	ConfirmedProgress bool
+	ConfirmedDone     bool
+	// Stale marks a block which was reset while being worked on, so that it's
+	// reset once the worker lets go of it
+	Stale         bool
+	LastAttempted time.Time
+	Delay         time.Duration
 }
 
 type Request struct {
 	BlockNumber  uint64
+	Finality     string
 	ResponseChan chan bool
 }
 
@@ -44,13 +50,37 @@ type Stat struct {
 	ResponseChan chan StatResponse
 }
 
+// FinalityUpdate carries the safe and finalized heads reported by the node.
+type FinalityUpdate struct {
+	Safe         uint64
+	Finalized    uint64
+	ResponseChan chan bool
+}
+
+type FinalityNext struct {
+	ResponseChan chan struct {
+		Status   bool
+		Number   uint64
+		Finality string
+	}
+}
+
+type Confirmations struct {
+	BlockNumber  uint64
+	ResponseChan chan uint64
+}
+
 type BlockProcessorQueue struct {
 	Blocks               map[uint64]*Block
 	StartedWith          uint64
 	TotalInserted        uint64
 	LatestBlock          uint64
+	SafeBlock            uint64
+	FinalizedBlock       uint64
+	Policy               ConfirmationPolicy
 	Total                uint64
 	PutChan              chan Request
+	ScheduleChan         chan Request
 	CanPublishChan       chan Request
 	PublishedChan        chan Request
 	InsertedChan         chan Request
@@ -62,6 +92,11 @@ type BlockProcessorQueue struct {
 	LatestChan           chan Update
 	UnconfirmedNextChan  chan Next
 	ConfirmedNextChan    chan Next
+	ResetChan            chan Request
+	FinalityChan         chan FinalityUpdate
+	FinalityNextChan     chan FinalityNext
+	FinalityFailedChan   chan Request
+	ConfirmationsChan    chan Confirmations
 }
 
 func (b *Block) SetDelay() {
@@ -80,14 +115,20 @@ func (b *Block) CanAttempt() bool {
 	return time.Now().UTC().After(b.LastAttempted.Add(b.Delay))
 }
 
-func New(startedWith uint64) *BlockProcessorQueue {
+func (b *Block) InProgress() bool {
+	return b.UnconfirmedProgress || b.ConfirmedProgress || b.FinalityProgress
+}
+
+func New(startedWith uint64, policy ConfirmationPolicy) *BlockProcessorQueue {
 	return &BlockProcessorQueue{
 		Blocks:               make(map[uint64]*Block),
 		StartedWith:          startedWith,
+		Policy:               policy,
 		TotalInserted:        0,
 		LatestBlock:          0,
 		Total:                0,
 		PutChan:              make(chan Request, 128),
+		ScheduleChan:         make(chan Request, 128),
 		CanPublishChan:       make(chan Request, 128),
 		PublishedChan:        make(chan Request, 128),
 		InsertedChan:         make(chan Request, 128),
@@ -99,6 +140,11 @@ func New(startedWith uint64) *BlockProcessorQueue {
 		LatestChan:           make(chan Update, 1),
 		UnconfirmedNextChan:  make(chan Next, 1),
 		ConfirmedNextChan:    make(chan Next, 1),
+		ResetChan:            make(chan Request, 128),
+		FinalityChan:         make(chan FinalityUpdate, 1),
+		FinalityNextChan:     make(chan FinalityNext, 1),
+		FinalityFailedChan:   make(chan Request, 128),
+		ConfirmationsChan:    make(chan Confirmations, 128),
 	}
 }
 
@@ -115,11 +161,29 @@ func (q *BlockProcessorQueue) Put(block uint64) bool {
 	return <-resp
 }
 
-func (q *BlockProcessorQueue) CanPublish(block uint64) bool {
+// Schedule queues a block without claiming it, for ProcessQueue to pick it
+// up right away, along with every other block waiting to be processed.
+func (q *BlockProcessorQueue) Schedule(block uint64) bool {
+	resp := make(chan bool)
+
+	req := Request{
+		BlockNumber:  block,
+		ResponseChan: resp,
+	}
+
+	q.ScheduleChan <- req
+
+	return <-resp
+}
+
+// CanPublish tells whether the block has completed the phase of the given
+// finality, without having been published with it yet.
+func (q *BlockProcessorQueue) CanPublish(block uint64, finality string) bool {
 	resp := make(chan bool)
 
 	req := Request{
 		BlockNumber:  block,
+		Finality:     finality,
 		ResponseChan: resp,
 	}
 
@@ -127,11 +191,12 @@ func (q *BlockProcessorQueue) CanPublish(block uint64) bool {
 	return <-resp
 }
 
-func (q *BlockProcessorQueue) Published(block uint64) bool {
+func (q *BlockProcessorQueue) Published(block uint64, finality string) bool {
 	resp := make(chan bool)
 
 	req := Request{
 		BlockNumber:  block,
+		Finality:     finality,
 		ResponseChan: resp,
 	}
 
@@ -200,7 +265,24 @@ func (q *BlockProcessorQueue) ConfirmedDone(block uint64) bool {
 		ResponseChan: resp,
 	}
 
-	q.ConfirmedFailedChan <- req
+	q.ConfirmedDoneChan <- req
+
+	return <-resp
+}
+
+// Reset puts a block back at the start of the unconfirmed phase, so that it
+// gets processed again, e.g. when a chain reorganization has orphaned it.
+// A block being worked on is only marked stale, and reset once its worker is
+// done with it, returning false.
+func (q *BlockProcessorQueue) Reset(block uint64) bool {
+	resp := make(chan bool)
+
+	req := Request{
+		BlockNumber:  block,
+		ResponseChan: resp,
+	}
+
+	q.ResetChan <- req
 
 	return <-resp
 }
@@ -230,6 +312,72 @@ func (q *BlockProcessorQueue) Latest(num uint64) bool {
 	return <-resp
 }
 
+// Finality updates the safe and finalized heads, which blocks up to are
+// published with the matching finality.
+func (q *BlockProcessorQueue) Finality(safe uint64, finalized uint64) bool {
+	resp := make(chan bool)
+
+	req := FinalityUpdate{
+		Safe:         safe,
+		Finalized:    finalized,
+		ResponseChan: resp,
+	}
+
+	q.FinalityChan <- req
+
+	return <-resp
+}
+
+// FinalityFailed lets a block be picked again, after a while, for being
+// published with the finality it has reached since it was processed.
+func (q *BlockProcessorQueue) FinalityFailed(block uint64) bool {
+	resp := make(chan bool)
+
+	req := Request{
+		BlockNumber:  block,
+		ResponseChan: resp,
+	}
+
+	q.FinalityFailedChan <- req
+
+	return <-resp
+}
+
+// Confirmations returns how many blocks the chain has from the given one up
+// to the latest, counting itself.
+func (q *BlockProcessorQueue) Confirmations(block uint64) uint64 {
+	resp := make(chan uint64)
+
+	req := Confirmations{
+		BlockNumber:  block,
+		ResponseChan: resp,
+	}
+
+	q.ConfirmationsChan <- req
+
+	return <-resp
+}
+
+// FinalityNext picks a processed block which has become safe or finalized
+// after it was published, so that it's published again with that finality.
+func (q *BlockProcessorQueue) FinalityNext() (uint64, string, bool) {
+	resp := make(chan struct {
+		Status   bool
+		Number   uint64
+		Finality string
+	})
+
+	req := FinalityNext{
+		ResponseChan: resp,
+	}
+
+	q.FinalityNextChan <- req
+
+	result := <-resp
+
+	return result.Number, result.Finality, result.Status
+}
+
 func (q *BlockProcessorQueue) UnconfirmedNext() (uint64, bool) {
 	resp := make(chan struct {
 		Status bool
@@ -263,23 +411,66 @@ func (q *BlockProcessorQueue) ConfirmedNext() (uint64, bool) {
 	return result.Number, result.Status
 }
 
+// CanBeConfirmed tells whether the confirmation policy considers the block
+// confirmed, going by the heads last reported.
 func (q *BlockProcessorQueue) CanBeConfirmed(block uint64) bool {
-	var blockConfirmations int = 0
-
-	if os.Getenv("BLOCK_CONFIRMATIONS") != "" {
-		blockConfirmationsEnv, err := strconv.Atoi(os.Getenv("BLOCK_CONFIRMATIONS"))
-		if err != nil {
-			blockConfirmations = 0
-		} else {
-			blockConfirmations = blockConfirmationsEnv
-		}
+	head, ok := q.Policy.ConfirmedHead(Heads{
+		Latest:    q.LatestBlock,
+		Safe:      q.SafeBlock,
+		Finalized: q.FinalizedBlock,
+	})
+
+	return ok && block <= head
+}
+
+// HasReached tells whether the block has completed the phase of the given
+// finality.
+func (q *BlockProcessorQueue) HasReached(block uint64, finality string) bool {
+	switch finality {
+	case data.FinalityLatest:
+		return true
+	case data.FinalityConfirmed:
+		return q.CanBeConfirmed(block)
+	case data.FinalitySafe:
+		return block <= q.SafeBlock
+	case data.FinalityFinalized:
+		return block <= q.FinalizedBlock
 	}
 
-	if q.LatestBlock < uint64(blockConfirmations) {
+	return false
+}
+
+// isTrackingFinality tells whether the node has reported a finalized head,
+// without which blocks never become safe or finalized.
+func (q *BlockProcessorQueue) isTrackingFinality() bool {
+	return q.FinalizedBlock > 0
+}
+
+// isSettled tells whether nothing's left to be done with a block, which can
+// then be dropped from the queue.
+func (q *BlockProcessorQueue) isSettled(block *Block) bool {
+	if !block.ConfirmedDone {
 		return false
 	}
 
-	return q.LatestBlock-uint64(blockConfirmations) >= block
+	return !q.isTrackingFinality() || block.Published[data.FinalityFinalized]
+}
+
+// reset puts a block back at the start of the unconfirmed phase.
+func (q *BlockProcessorQueue) reset(number uint64) {
+	q.Blocks[number] = &Block{
+		Published:     make(map[string]bool),
+		LastAttempted: time.Now().UTC(),
+		Delay:         time.Duration(1) * time.Second,
+	}
+}
+
+// resetIfStale resets a block which was marked stale while being worked on,
+// as soon as no worker holds it anymore.
+func (q *BlockProcessorQueue) resetIfStale(number uint64) {
+	if block, ok := q.Blocks[number]; ok && block.Stale && !block.InProgress() {
+		q.reset(number)
+	}
 }
 
 func (q *BlockProcessorQueue) TotalBlocks() uint64 {
@@ -300,18 +491,31 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 			q.Blocks[req.BlockNumber] = &Block{
 				UnconfirmedProgress: true,
+				Published:           make(map[string]bool),
 				LastAttempted:       time.Now().UTC(),
 				Delay:               time.Duration(1) * time.Second,
 			}
 			req.ResponseChan <- true
 
+		case req := <-q.ScheduleChan:
+			if _, ok := q.Blocks[req.BlockNumber]; ok {
+				req.ResponseChan <- false
+				break
+			}
+
+			q.Blocks[req.BlockNumber] = &Block{
+				Published: make(map[string]bool),
+				Delay:     time.Duration(1) * time.Second,
+			}
+			req.ResponseChan <- true
+
 		case req := <-q.CanPublishChan:
 			block, ok := q.Blocks[req.BlockNumber]
 			if !ok {
 				req.ResponseChan <- false
 				break
 			}
-			req.ResponseChan <- !block.Published
+			req.ResponseChan <- !block.Stale && !block.Published[req.Finality] && q.HasReached(req.BlockNumber, req.Finality)
 
 		case req := <-q.PublishedChan:
 			block, ok := q.Blocks[req.BlockNumber]
@@ -319,7 +523,9 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 				req.ResponseChan <- false
 				break
 			}
-			block.Published = true
+			block.Published[req.Finality] = true
+			block.FinalityProgress = false
+			q.resetIfStale(req.BlockNumber)
 			req.ResponseChan <- true
 
 		case req := <-q.InsertedChan:
@@ -340,6 +546,7 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 			block.UnconfirmedProgress = false
 			block.SetDelay()
+			q.resetIfStale(req.BlockNumber)
 			req.ResponseChan <- true
 
 		case req := <-q.UnconfirmedDoneChan:
@@ -351,9 +558,12 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 			block.UnconfirmedProgress = false
 			block.UnconfirmedDone = true
-			block.ConfirmedDone = q.CanBeConfirmed(req.BlockNumber)
+			// Blocks which were published as confirmed by this pass
+			// already don't need the confirmed one
+			block.ConfirmedDone = block.Published[data.FinalityConfirmed]
 			block.ResetDelay()
 			block.SetLastAttempted()
+			q.resetIfStale(req.BlockNumber)
 			req.ResponseChan <- true
 
 		case req := <-q.ConfirmedFailedChan:
@@ -365,6 +575,7 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 			block.ConfirmedProgress = false
 			block.SetDelay()
+			q.resetIfStale(req.BlockNumber)
 			req.ResponseChan <- true
 
 		case req := <-q.ConfirmedDoneChan:
@@ -376,9 +587,33 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 			block.ConfirmedProgress = false
 			block.ConfirmedDone = true
+			q.resetIfStale(req.BlockNumber)
 
 			req.ResponseChan <- true
 
+		case req := <-q.FinalityFailedChan:
+			block, ok := q.Blocks[req.BlockNumber]
+			if !ok {
+				req.ResponseChan <- false
+				break
+			}
+
+			block.FinalityProgress = false
+			block.SetDelay()
+			block.SetLastAttempted()
+			q.resetIfStale(req.BlockNumber)
+			req.ResponseChan <- true
+
+		case req := <-q.ResetChan:
+			if block, ok := q.Blocks[req.BlockNumber]; ok && block.InProgress() {
+				block.Stale = true
+				req.ResponseChan <- false
+				break
+			}
+
+			q.reset(req.BlockNumber)
+			req.ResponseChan <- true
+
 		case nxt := <-q.UnconfirmedNextChan:
 			var selected uint64
 			var found bool
@@ -453,6 +688,62 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 				Number uint64
 			}{Status: true, Number: selected}
 
+		case nxt := <-q.FinalityNextChan:
+			var selected uint64
+			var finality string
+
+		FINALITY:
+			for k, block := range q.Blocks {
+
+				if !block.UnconfirmedDone || block.UnconfirmedProgress || block.ConfirmedProgress || block.FinalityProgress {
+					continue
+				}
+
+				if !block.CanAttempt() {
+					continue
+				}
+
+				for _, f := range []string{data.FinalitySafe, data.FinalityFinalized} {
+					if !block.Published[f] && q.HasReached(k, f) {
+						selected = k
+						finality = f
+						break FINALITY
+					}
+				}
+			}
+
+			if finality == "" {
+				nxt.ResponseChan <- struct {
+					Status   bool
+					Number   uint64
+					Finality string
+				}{Status: false}
+				break
+			}
+
+			q.Blocks[selected].SetLastAttempted()
+			q.Blocks[selected].FinalityProgress = true
+
+			nxt.ResponseChan <- struct {
+				Status   bool
+				Number   uint64
+				Finality string
+			}{Status: true, Number: selected, Finality: finality}
+
+		case req := <-q.ConfirmationsChan:
+			if q.LatestBlock < req.BlockNumber {
+				req.ResponseChan <- 1
+				break
+			}
+
+			req.ResponseChan <- q.LatestBlock - req.BlockNumber + 1
+
+		case udt := <-q.FinalityChan:
+
+			q.SafeBlock = udt.Safe
+			q.FinalizedBlock = udt.Finalized
+			udt.ResponseChan <- true
+
 		case req := <-q.StatChan:
 
 			var stat StatResponse
@@ -490,7 +781,7 @@ func (q *BlockProcessorQueue) Start(ctx context.Context) {
 
 		case <-time.After(time.Duration(1) * time.Second):
 			for k := range q.Blocks {
-				if q.Blocks[k].ConfirmedDone {
+				if q.isSettled(q.Blocks[k]) {
 					delete(q.Blocks, k)
 					q.Total++
 				}
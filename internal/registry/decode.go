@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+// DecodeEvent decodes an event using its contract's ABI, falling back to
+// any known event with the same topic0. It returns nil when the event can't
+// be decoded, in which case only its raw data is to be shown.
+func (r *Registry) DecodeEvent(event *data.Event) *data.Decoded {
+	if r == nil || len(event.Topics) == 0 {
+		return nil
+	}
+
+	topic := common.HexToHash(event.Topics[0])
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	candidates := make([]abi.Event, 0, 1)
+
+	if contract, ok := r.contracts[event.Origin]; ok {
+		if e, err := contract.EventByID(topic); err == nil {
+			candidates = append(candidates, *e)
+		}
+	}
+
+	candidates = append(candidates, r.events[topic]...)
+
+	for _, candidate := range candidates {
+		if decoded, err := decodeEvent(candidate, event); err == nil {
+			return decoded
+		}
+	}
+
+	return nil
+}
+
+// DecodeTransaction decodes the calldata of a transaction the same way
+// DecodeEvent does events, by the 4-byte selector of the called method.
+func (r *Registry) DecodeTransaction(tx *data.Transaction) *data.Decoded {
+	if r == nil || len(tx.Data) < 4 || tx.To == "" {
+		return nil
+	}
+
+	var selector [4]byte
+	copy(selector[:], tx.Data[:4])
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	candidates := make([]abi.Method, 0, 1)
+
+	if contract, ok := r.contracts[tx.To]; ok {
+		if m, err := contract.MethodById(selector[:]); err == nil {
+			candidates = append(candidates, *m)
+		}
+	}
+
+	candidates = append(candidates, r.methods[selector]...)
+
+	for _, candidate := range candidates {
+		values, err := candidate.Inputs.Unpack(tx.Data[4:])
+		if err != nil {
+			continue
+		}
+
+		decoded := &data.Decoded{
+			Name:      candidate.RawName,
+			Signature: candidate.Sig,
+			Args:      make([]*data.DecodedArg, 0, len(values)),
+		}
+
+		for i, input := range candidate.Inputs {
+			decoded.Args = append(decoded.Args, newArg(input, i, values[i]))
+		}
+
+		return decoded
+	}
+
+	return nil
+}
+
+func (r *Registry) DecodeEvents(events []*data.Event) {
+	for _, event := range events {
+		event.Decoded = r.DecodeEvent(event)
+	}
+}
+
+func (r *Registry) DecodeTransactions(txs []*data.Transaction) {
+	for _, tx := range txs {
+		tx.Decoded = r.DecodeTransaction(tx)
+	}
+}
+
+func decodeEvent(e abi.Event, event *data.Event) (*data.Decoded, error) {
+	indexed := 0
+	for _, input := range e.Inputs {
+		if input.Indexed {
+			indexed++
+		}
+	}
+
+	if indexed != len(event.Topics)-1 {
+		return nil, errors.New("topic count mismatch")
+	}
+
+	values, err := e.Inputs.Unpack(event.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := &data.Decoded{
+		Name:      e.RawName,
+		Signature: e.Sig,
+		Args:      make([]*data.DecodedArg, 0, len(e.Inputs)),
+	}
+
+	topic := 1
+
+	for i, input := range e.Inputs {
+		if !input.Indexed {
+			decoded.Args = append(decoded.Args, newArg(input, i, values[0]))
+			values = values[1:]
+			continue
+		}
+
+		arg, err := decodeTopic(input, i, event.Topics[topic])
+		if err != nil {
+			return nil, err
+		}
+
+		decoded.Args = append(decoded.Args, arg)
+		topic++
+	}
+
+	return decoded, nil
+}
+
+// decodeTopic unpacks an indexed argument. Dynamic types are indexed by the
+// hash of their value, which is all there is to show of them.
+func decodeTopic(input abi.Argument, i int, topic string) (*data.DecodedArg, error) {
+	raw := common.FromHex(topic)
+
+	switch input.Type.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		arg := newArg(input, i, nil)
+		arg.Value = common.BytesToHash(raw).Hex()
+		return arg, nil
+	}
+
+	values, err := (abi.Arguments{{Type: input.Type}}).Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return newArg(input, i, values[0]), nil
+}
+
+func newArg(input abi.Argument, i int, value interface{}) *data.DecodedArg {
+	name := input.Name
+	if name == "" {
+		name = fmt.Sprintf("arg%d", i)
+	}
+
+	return &data.DecodedArg{
+		Name:    name,
+		Type:    input.Type.String(),
+		Indexed: input.Indexed,
+		Value:   format(reflect.ValueOf(value)),
+	}
+}
+
+// format turns a decoded value into one which encodes to JSON without
+// losing precision: integers become decimal strings and bytes become hex.
+func format(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case common.Hash:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.String:
+		return v.Interface()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v.Interface())
+
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			raw := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(raw), v)
+			return hexutil.Encode(raw)
+		}
+
+		fallthrough
+
+	case reflect.Slice:
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, format(v.Index(i)))
+		}
+
+		return values
+
+	case reflect.Struct:
+		// Tuples are decoded into structs, tagged with their component names
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Tag.Get("json")
+			if name == "" {
+				name = v.Type().Field(i).Name
+			}
+
+			fields[name] = format(v.Field(i))
+		}
+
+		return fields
+
+	case reflect.Ptr, reflect.Interface:
+		return format(v.Elem())
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Registry holds contract ABIs, both attached to the contract they belong to
+// and indexed by event topic0 and method selector, so that payloads of
+// contracts without a known ABI can still be decoded by signature.
+type Registry struct {
+	lock      sync.RWMutex
+	contracts map[string]*abi.ABI
+	events    map[common.Hash][]abi.Event
+	methods   map[[4]byte][]abi.Method
+}
+
+func New() *Registry {
+	return &Registry{
+		contracts: make(map[string]*abi.ABI),
+		events:    make(map[common.Hash][]abi.Event),
+		methods:   make(map[[4]byte][]abi.Method),
+	}
+}
+
+// Load builds a registry out of uploaded ABIs, followed by those found in
+// the directory pointed to by ABI_DIR when it's set.
+func Load(_db *gorm.DB) (*Registry, error) {
+	r := New()
+
+	abis, err := db.GetContractABIs(_db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range abis {
+		if err := r.Add(a.Address, []byte(a.ABI)); err != nil {
+			logger.S().Warnf("Skipping stored ABI %d: %s", a.ID, err.Error())
+		}
+	}
+
+	if dir := os.Getenv("ABI_DIR"); dir != "" {
+		if err := r.LoadDirectory(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Parse reads an ABI, either as a plain JSON array or wrapped in the "abi"
+// field of a compiler artifact, as Hardhat and Foundry write them.
+func Parse(raw []byte) (*abi.ABI, error) {
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) != 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &artifact); err != nil {
+			return nil, err
+		}
+
+		if len(artifact.ABI) == 0 {
+			return nil, errors.New("artifact has no abi field")
+		}
+
+		raw = artifact.ABI
+	}
+
+	parsed, err := abi.JSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+// Add registers an ABI, attaching it to the contract when an address is
+// given. A later ABI of the same contract replaces the earlier one.
+func (r *Registry) Add(address string, raw []byte) error {
+	parsed, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if address != "" {
+		r.contracts[common.HexToAddress(address).Hex()] = parsed
+	}
+
+	for _, event := range parsed.Events {
+		if !event.Anonymous && !hasEvent(r.events[event.ID], event) {
+			r.events[event.ID] = append(r.events[event.ID], event)
+		}
+	}
+
+	for _, method := range parsed.Methods {
+		var selector [4]byte
+		copy(selector[:], method.ID)
+
+		if !hasMethod(r.methods[selector], method) {
+			r.methods[selector] = append(r.methods[selector], method)
+		}
+	}
+
+	return nil
+}
+
+// LoadDirectory registers every JSON file of the directory. Files named
+// after a contract address, e.g. 0xA0b8...eB48.json, are attached to it,
+// the others are only used for decoding by signature.
+func (r *Registry) LoadDirectory(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		address := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if !util.IsValidAddress(address) {
+			address = ""
+		}
+
+		if err := r.Add(address, raw); err != nil {
+			return fmt.Errorf("failed to load ABI from %s: %w", file, err)
+		}
+	}
+
+	logger.S().Infof("Loaded %d ABIs from %s", len(files), dir)
+	return nil
+}
+
+// hasEvent tells whether an event with the same layout was seen already.
+// ERC-20 and ERC-721 share Transfer's signature, but not which of its
+// arguments are indexed, so both have to be kept.
+func hasEvent(events []abi.Event, event abi.Event) bool {
+	for _, e := range events {
+		if e.String() == event.String() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasMethod(methods []abi.Method, method abi.Method) bool {
+	for _, m := range methods {
+		if m.String() == method.String() {
+			return true
+		}
+	}
+
+	return false
+}
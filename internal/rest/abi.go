@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// UploadABIRequest carries an ABI, either as a plain array or as a compiler
+// artifact, optionally attached to a contract.
+type UploadABIRequest struct {
+	Address string          `json:"address"`
+	ABI     json.RawMessage `json:"abi"`
+}
+
+func (s *Server) abi(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+
+	if !util.IsValidAddress(address) {
+		writeError(w, http.StatusBadRequest, "Bad contract address")
+		return
+	}
+
+	abis, err := db.GetContractABIsByAddress(s.DB, common.HexToAddress(address).Hex())
+	if err != nil {
+		logger.S().Errorf("Failed to query ABIs: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query ABIs")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, abis.ToJSON())
+}
+
+func (s *Server) uploadABI(w http.ResponseWriter, r *http.Request) {
+	var req UploadABIRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad request body")
+		return
+	}
+
+	if req.Address != "" {
+		if !util.IsValidAddress(req.Address) {
+			writeError(w, http.StatusBadRequest, "Bad contract address")
+			return
+		}
+
+		req.Address = common.HexToAddress(req.Address).Hex()
+	}
+
+	if _, err := registry.Parse(req.ABI); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad ABI: "+err.Error())
+		return
+	}
+
+	contractABI := &data.ContractABI{
+		Address: req.Address,
+		ABI:     string(req.ABI),
+	}
+
+	if err := db.StoreContractABI(s.DB, contractABI); err != nil {
+		logger.S().Errorf("Failed to store ABI: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to store ABI")
+		return
+	}
+
+	if err := s.Registry.Add(contractABI.Address, req.ABI); err != nil {
+		logger.S().Errorf("Failed to register ABI: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to register ABI")
+		return
+	}
+
+	writeRaw(w, http.StatusCreated, contractABI.ToJSON())
+}
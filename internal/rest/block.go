@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func (s *Server) block(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	hash := query.Get("hash")
+	number := query.Get("number")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	switch {
+	case hash != "":
+		if !util.IsValidHash(hash) {
+			writeError(w, http.StatusBadRequest, "Bad block hash")
+			return
+		}
+
+		block, err := db.GetBlockByHash(s.DB, common.HexToHash(hash).Hex())
+		if err != nil {
+			logger.S().Errorf("Failed to query block by hash: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query block")
+			return
+		}
+
+		if block == nil {
+			writeError(w, http.StatusNotFound, "Block not found")
+			return
+		}
+
+		writeRaw(w, http.StatusOK, block.ToJSON())
+
+	case number != "":
+		num, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad block number")
+			return
+		}
+
+		block, err := db.GetBlockByNumber(s.DB, num)
+		if err != nil {
+			logger.S().Errorf("Failed to query block by number: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query block")
+			return
+		}
+
+		if block == nil {
+			writeError(w, http.StatusNotFound, "Block not found")
+			return
+		}
+
+		writeRaw(w, http.StatusOK, block.ToJSON())
+
+	case fromBlock != "" && toBlock != "":
+		from, to, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		blocks, err := db.GetBlocksByNumberRange(s.DB, from, to)
+		if err != nil {
+			logger.S().Errorf("Failed to query blocks by range: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query blocks")
+			return
+		}
+
+		writeRaw(w, http.StatusOK, blocks.ToJSON())
+
+	default:
+		writeError(w, http.StatusBadRequest, "Expected one of hash, number or fromBlock & toBlock")
+	}
+}
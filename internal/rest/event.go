@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func (s *Server) event(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	contract := query.Get("contract")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	topics := []string{
+		query.Get("topic0"),
+		query.Get("topic1"),
+		query.Get("topic2"),
+		query.Get("topic3"),
+	}
+
+	if fromBlock == "" || toBlock == "" {
+		writeError(w, http.StatusBadRequest, "Expected fromBlock & toBlock")
+		return
+	}
+
+	if contract != "" {
+		if !util.IsValidAddress(contract) {
+			writeError(w, http.StatusBadRequest, "Bad contract address")
+			return
+		}
+
+		contract = common.HexToAddress(contract).Hex()
+	}
+
+	for i, topic := range topics {
+		if topic == "" {
+			continue
+		}
+
+		normalized, ok := c.NormalizeEventTopic(i, topic)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "Bad event topic")
+			return
+		}
+
+		topics[i] = normalized
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, err := db.GetEventsByBlockNumberRange(s.DB, contract, c.CreateEventTopicMap(topics), start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query events: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query events")
+		return
+	}
+
+	s.Registry.DecodeEvents(events.Events)
+
+	writeRaw(w, http.StatusOK, events.ToJSON())
+}
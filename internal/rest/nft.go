@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"math/big"
+	"net/http"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// parseTokenID accepts a token id in decimal or 0x prefixed hex and returns
+// it in decimal, as stored.
+func parseTokenID(id string) (string, bool) {
+	value, ok := new(big.Int).SetString(id, 0)
+	if !ok || value.Sign() < 0 {
+		return "", false
+	}
+
+	return value.String(), true
+}
+
+func (s *Server) nftHoldings(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	owner := query.Get("owner")
+	contract := query.Get("contract")
+
+	if owner == "" {
+		writeError(w, http.StatusBadRequest, "Expected owner, optionally with contract")
+		return
+	}
+
+	if !normalizeAddresses(&owner, &contract) {
+		writeError(w, http.StatusBadRequest, "Bad address")
+		return
+	}
+
+	owners, err := db.GetNFTHoldings(s.DB, owner, contract)
+	if err != nil {
+		logger.S().Errorf("Failed to query nft holdings: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query nft holdings")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, owners.ToJSON())
+}
+
+func (s *Server) nftOwner(w http.ResponseWriter, r *http.Request) {
+	contract, id, ok := nftToken(w, r)
+	if !ok {
+		return
+	}
+
+	owners, err := db.GetNFTOwners(s.DB, contract, id)
+	if err != nil {
+		logger.S().Errorf("Failed to query nft owners: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query nft owners")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, owners.ToJSON())
+}
+
+func (s *Server) nftProvenance(w http.ResponseWriter, r *http.Request) {
+	contract, id, ok := nftToken(w, r)
+	if !ok {
+		return
+	}
+
+	transfers, err := db.GetNFTProvenance(s.DB, contract, id)
+	if err != nil {
+		logger.S().Errorf("Failed to query nft provenance: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query nft provenance")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, transfers.ToJSON())
+}
+
+// nftToken reads the contract and token id a request is about, writing the
+// error response itself when they're missing or malformed.
+func nftToken(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	query := r.URL.Query()
+
+	contract := query.Get("contract")
+	tokenID := query.Get("tokenId")
+
+	if contract == "" || tokenID == "" {
+		writeError(w, http.StatusBadRequest, "Expected contract & tokenId")
+		return "", "", false
+	}
+
+	if !normalizeAddresses(&contract) {
+		writeError(w, http.StatusBadRequest, "Bad contract address")
+		return "", "", false
+	}
+
+	id, ok := parseTokenID(tokenID)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad token id")
+		return "", "", false
+	}
+
+	return contract, id, true
+}
@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/registry"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+type Server struct {
+	DB       *gorm.DB
+	Redis    *data.RedisInfo
+	Registry *registry.Registry
+}
+
+type ErrorResponse struct {
+	Msg string `json:"msg"`
+}
+
+type StatusResponse struct {
+	BlockCount  uint64 `json:"blockCount"`
+	LatestBlock uint64 `json:"latestBlock"`
+	OldestBlock uint64 `json:"oldestBlock"`
+}
+
+func NewServer(_db *gorm.DB, redis *data.RedisInfo, registry *registry.Registry) *Server {
+	return &Server{
+		DB:       _db,
+		Redis:    redis,
+		Registry: registry,
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/status", s.status)
+	mux.HandleFunc("GET /v1/block", s.block)
+	mux.HandleFunc("GET /v1/transaction", s.transaction)
+	mux.HandleFunc("GET /v1/transaction/internal", s.internalTransaction)
+	mux.HandleFunc("GET /v1/receipt", s.receipt)
+	mux.HandleFunc("GET /v1/event", s.event)
+	mux.HandleFunc("GET /v1/withdrawal", s.withdrawal)
+	mux.HandleFunc("GET /v1/token", s.token)
+	mux.HandleFunc("GET /v1/token/transfer", s.tokenTransfer)
+	mux.HandleFunc("GET /v1/token/approval", s.tokenApproval)
+	mux.HandleFunc("GET /v1/token/balance", s.tokenBalance)
+	mux.HandleFunc("GET /v1/nft/holdings", s.nftHoldings)
+	mux.HandleFunc("GET /v1/nft/owner", s.nftOwner)
+	mux.HandleFunc("GET /v1/nft/provenance", s.nftProvenance)
+	mux.HandleFunc("GET /v1/abi", s.abi)
+	mux.HandleFunc("POST /v1/abi", s.uploadABI)
+	mux.HandleFunc("GET /v1/webhook", s.webhook)
+	mux.HandleFunc("POST /v1/webhook", s.registerWebhook)
+	mux.HandleFunc("DELETE /v1/webhook", s.deleteWebhook)
+	mux.HandleFunc("GET /v1/webhook/delivery", s.webhookDelivery)
+	mux.HandleFunc("GET /v1/webhook/deadletter", s.webhookDeadLetter)
+	mux.HandleFunc("GET /v1/ws", s.ws)
+	mux.HandleFunc("POST /v1/stream/group", s.streamGroup)
+
+	return mux
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &StatusResponse{
+		BlockCount:  db.GetBlockCount(s.DB),
+		LatestBlock: db.GetCurrentBlockNumber(s.DB),
+		OldestBlock: db.GetCurrentOldestBlockNumber(s.DB),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.S().Errorf("Failed to write response: %v", err.Error())
+	}
+}
+
+// writeRaw sends a body which was already encoded by one of the data
+// models' JSON encoders.
+func writeRaw(w http.ResponseWriter, code int, body []byte) {
+	if body == nil {
+		writeError(w, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if _, err := w.Write(body); err != nil {
+		logger.S().Errorf("Failed to write response: %v", err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, &ErrorResponse{Msg: msg})
+}
+
+func getMaxQueryRange() uint64 {
+	if v := os.Getenv("MAX_QUERY_RANGE"); v != "" {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			return limit
+		}
+	}
+
+	return 100
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// normalizeAddresses checksums every non-empty address, reporting false if
+// any of them is malformed.
+func normalizeAddresses(addresses ...*string) bool {
+	for _, address := range addresses {
+		if *address == "" {
+			continue
+		}
+
+		if !util.IsValidAddress(*address) {
+			return false
+		}
+
+		*address = common.HexToAddress(*address).Hex()
+	}
+
+	return true
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+
+	if !util.IsValidAddress(address) {
+		writeError(w, http.StatusBadRequest, "Bad token address")
+		return
+	}
+
+	token, err := db.GetTokenByAddress(s.DB, common.HexToAddress(address).Hex())
+	if err != nil {
+		logger.S().Errorf("Failed to query token: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query token")
+		return
+	}
+
+	if token == nil {
+		writeError(w, http.StatusNotFound, "Token not found")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, token.ToJSON())
+}
+
+func (s *Server) tokenTransfer(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	token := query.Get("token")
+	from := query.Get("from")
+	to := query.Get("to")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	if fromBlock == "" || toBlock == "" || (token == "" && from == "" && to == "") {
+		writeError(w, http.StatusBadRequest, "Expected token, from and/or to with fromBlock & toBlock")
+		return
+	}
+
+	if !normalizeAddresses(&token, &from, &to) {
+		writeError(w, http.StatusBadRequest, "Bad address")
+		return
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transfers, err := db.GetTokenTransfersByBlockNumberRange(s.DB, token, from, to, start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query token transfers: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query token transfers")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, transfers.ToJSON())
+}
+
+func (s *Server) tokenApproval(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	token := query.Get("token")
+	owner := query.Get("owner")
+	spender := query.Get("spender")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	if fromBlock == "" || toBlock == "" || (token == "" && owner == "" && spender == "") {
+		writeError(w, http.StatusBadRequest, "Expected token, owner and/or spender with fromBlock & toBlock")
+		return
+	}
+
+	if !normalizeAddresses(&token, &owner, &spender) {
+		writeError(w, http.StatusBadRequest, "Bad address")
+		return
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	approvals, err := db.GetTokenApprovalsByBlockNumberRange(s.DB, token, owner, spender, start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query token approvals: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query token approvals")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, approvals.ToJSON())
+}
+
+func (s *Server) tokenBalance(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	holder := query.Get("holder")
+	token := query.Get("token")
+
+	if holder == "" {
+		writeError(w, http.StatusBadRequest, "Expected holder, optionally with token")
+		return
+	}
+
+	if !normalizeAddresses(&holder, &token) {
+		writeError(w, http.StatusBadRequest, "Bad address")
+		return
+	}
+
+	balances, err := db.GetTokenBalances(s.DB, holder, token)
+	if err != nil {
+		logger.S().Errorf("Failed to query token balances: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query token balances")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, balances.ToJSON())
+}
@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func (s *Server) transaction(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	hash := query.Get("hash")
+	from := query.Get("from")
+	to := query.Get("to")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	if hash != "" {
+		if !util.IsValidHash(hash) {
+			writeError(w, http.StatusBadRequest, "Bad transaction hash")
+			return
+		}
+
+		tx, err := db.GetTransactionByHash(s.DB, common.HexToHash(hash).Hex())
+		if err != nil {
+			logger.S().Errorf("Failed to query transaction by hash: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query transaction")
+			return
+		}
+
+		if tx == nil {
+			writeError(w, http.StatusNotFound, "Transaction not found")
+			return
+		}
+
+		if err := db.AttachReceipts(s.DB, []*data.Transaction{tx}); err != nil {
+			logger.S().Errorf("Failed to query transaction receipt: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query transaction")
+			return
+		}
+
+		tx.Decoded = s.Registry.DecodeTransaction(tx)
+
+		writeRaw(w, http.StatusOK, tx.ToJSON())
+		return
+	}
+
+	if fromBlock == "" || toBlock == "" || (from == "" && to == "") {
+		writeError(w, http.StatusBadRequest, "Expected hash, or from and/or to with fromBlock & toBlock")
+		return
+	}
+
+	if (from != "" && !util.IsValidAddress(from)) || (to != "" && !util.IsValidAddress(to)) {
+		writeError(w, http.StatusBadRequest, "Bad account address")
+		return
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if from != "" {
+		from = common.HexToAddress(from).Hex()
+	}
+
+	if to != "" {
+		to = common.HexToAddress(to).Hex()
+	}
+
+	txs, err := db.GetTransactionsByBlockNumberRange(s.DB, from, to, start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query transactions: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query transactions")
+		return
+	}
+
+	if err := db.AttachReceipts(s.DB, txs.Transactions); err != nil {
+		logger.S().Errorf("Failed to query transaction receipts: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query transactions")
+		return
+	}
+
+	s.Registry.DecodeTransactions(txs.Transactions)
+
+	writeRaw(w, http.StatusOK, txs.ToJSON())
+}
+
+func (s *Server) receipt(w http.ResponseWriter, r *http.Request) {
+	hash := r.URL.Query().Get("hash")
+
+	if !util.IsValidHash(hash) {
+		writeError(w, http.StatusBadRequest, "Bad transaction hash")
+		return
+	}
+
+	receipt, err := db.GetReceiptByTransactionHash(s.DB, common.HexToHash(hash).Hex())
+	if err != nil {
+		logger.S().Errorf("Failed to query receipt by transaction hash: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query receipt")
+		return
+	}
+
+	if receipt == nil {
+		writeError(w, http.StatusNotFound, "Receipt not found")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, receipt.ToJSON())
+}
+
+func (s *Server) internalTransaction(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	hash := query.Get("hash")
+	from := query.Get("from")
+	to := query.Get("to")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	if hash != "" {
+		if !util.IsValidHash(hash) {
+			writeError(w, http.StatusBadRequest, "Bad transaction hash")
+			return
+		}
+
+		txs, err := db.GetInternalTransactionsByTransactionHash(s.DB, common.HexToHash(hash).Hex())
+		if err != nil {
+			logger.S().Errorf("Failed to query internal transactions by hash: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query internal transactions")
+			return
+		}
+
+		writeRaw(w, http.StatusOK, txs.ToJSON())
+		return
+	}
+
+	if fromBlock == "" || toBlock == "" || (from == "" && to == "") {
+		writeError(w, http.StatusBadRequest, "Expected hash, or from and/or to with fromBlock & toBlock")
+		return
+	}
+
+	if (from != "" && !util.IsValidAddress(from)) || (to != "" && !util.IsValidAddress(to)) {
+		writeError(w, http.StatusBadRequest, "Bad account address")
+		return
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if from != "" {
+		from = common.HexToAddress(from).Hex()
+	}
+
+	if to != "" {
+		to = common.HexToAddress(to).Hex()
+	}
+
+	txs, err := db.GetInternalTransactionsByBlockNumberRange(s.DB, from, to, start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query internal transactions: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query internal transactions")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, txs.ToJSON())
+}
@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/pubsub"
+	"github.com/kunalsinghdadhwal/nyx/internal/webhook"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// webhookLogLimit caps how many deliveries or dead letters are returned.
+const webhookLogLimit = 100
+
+// RegisterWebhookRequest registers a URL for data matching a subscription,
+// written as it would be over the WebSocket API, including the finality.
+type RegisterWebhookRequest struct {
+	URL          string               `json:"url"`
+	Subscription string               `json:"subscription"`
+	Filter       *pubsub.FilterObject `json:"filter,omitempty"`
+	Finality     string               `json:"finality,omitempty"`
+}
+
+// RegisterWebhookResponse carries the secret deliveries are signed with,
+// which isn't shown again afterwards.
+type RegisterWebhookResponse struct {
+	Webhook *data.Webhook `json:"webhook"`
+	Secret  string        `json:"secret"`
+}
+
+// webhookID reads the id query parameter, writing an error response when
+// it's missing or malformed.
+func webhookID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad webhook id")
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+func (s *Server) registerWebhook(w http.ResponseWriter, r *http.Request) {
+	var req RegisterWebhookRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad request body")
+		return
+	}
+
+	if err := webhook.CheckURL(r.Context(), req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad webhook URL: "+err.Error())
+		return
+	}
+
+	hook := &data.Webhook{
+		URL:          req.URL,
+		Subscription: req.Subscription,
+		Finality:     req.Finality,
+	}
+
+	if req.Filter != nil {
+		filter, err := json.Marshal(req.Filter)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad filter")
+			return
+		}
+
+		hook.Filter = string(filter)
+	}
+
+	if _, err := webhook.Subscription(hook); err != nil {
+		writeError(w, http.StatusBadRequest, "Bad subscription: "+err.Error())
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		logger.S().Errorf("Failed to generate webhook secret: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to register webhook")
+		return
+	}
+
+	hook.Secret = secret
+
+	if err := db.StoreWebhook(s.DB, hook); err != nil {
+		logger.S().Errorf("Failed to store webhook: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to register webhook")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &RegisterWebhookResponse{
+		Webhook: hook,
+		Secret:  secret,
+	})
+}
+
+// webhook returns the webhook with the given id, or every webhook when no
+// id is given.
+func (s *Server) webhook(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("id") == "" {
+		webhooks, err := db.GetWebhooks(s.DB)
+		if err != nil {
+			logger.S().Errorf("Failed to query webhooks: %s", err.Error())
+			writeError(w, http.StatusInternalServerError, "Failed to query webhooks")
+			return
+		}
+
+		writeRaw(w, http.StatusOK, (&data.Webhooks{Webhooks: webhooks}).ToJSON())
+		return
+	}
+
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	hook, err := db.GetWebhookByID(s.DB, id)
+	if err != nil {
+		logger.S().Errorf("Failed to query webhook: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query webhook")
+		return
+	}
+
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, hook.ToJSON())
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := db.DeleteWebhook(s.DB, id)
+	if err != nil {
+		logger.S().Errorf("Failed to delete webhook: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	if !deleted {
+		writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookDelivery returns the latest deliveries of a webhook, optionally
+// only those with the given status.
+func (s *Server) webhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+
+	switch status {
+	case "", data.DeliveryPending, data.DeliveryDelivered, data.DeliveryDead:
+	default:
+		writeError(w, http.StatusBadRequest, "Bad delivery status")
+		return
+	}
+
+	deliveries, err := db.GetWebhookDeliveries(s.DB, id, status, webhookLogLimit)
+	if err != nil {
+		logger.S().Errorf("Failed to query webhook deliveries: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query webhook deliveries")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, deliveries.ToJSON())
+}
+
+func (s *Server) webhookDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	deadLetters, err := db.GetWebhookDeadLetters(s.DB, id, webhookLogLimit)
+	if err != nil {
+		logger.S().Errorf("Failed to query webhook dead letters: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query webhook dead letters")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, deadLetters.ToJSON())
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/util"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+func (s *Server) withdrawal(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	address := query.Get("address")
+	validator := query.Get("validator")
+	fromBlock := query.Get("fromBlock")
+	toBlock := query.Get("toBlock")
+
+	if fromBlock == "" || toBlock == "" || (address == "" && validator == "") {
+		writeError(w, http.StatusBadRequest, "Expected address and/or validator with fromBlock & toBlock")
+		return
+	}
+
+	if address != "" {
+		if !util.IsValidAddress(address) {
+			writeError(w, http.StatusBadRequest, "Bad withdrawal address")
+			return
+		}
+
+		address = common.HexToAddress(address).Hex()
+	}
+
+	var validatorIndex *uint64
+
+	if validator != "" {
+		index, err := strconv.ParseUint(validator, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Bad validator index")
+			return
+		}
+
+		validatorIndex = &index
+	}
+
+	start, end, err := c.RangeChecker(fromBlock, toBlock, getMaxQueryRange())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	withdrawals, err := db.GetWithdrawalsByBlockNumberRange(s.DB, address, validatorIndex, start, end)
+	if err != nil {
+		logger.S().Errorf("Failed to query withdrawals: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to query withdrawals")
+		return
+	}
+
+	writeRaw(w, http.StatusOK, withdrawals.ToJSON())
+}
@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/kunalsinghdadhwal/nyx/internal/pubsub"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// streamIDPattern matches the ID of a Redis stream entry, with or without
+// its sequence number.
+var streamIDPattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ws upgrades the connection and serves subscribe/unsubscribe requests over
+// it, until the client goes away. When topics go over Redis Streams, the
+// optional `group` query parameter names a consumer group to resume, as
+// issued by POST /v1/stream/group, which only one connection can read at a
+// time. Each `from` parameter, as `<stream>:<id>`, is the ID of the last
+// entry received from a stream, as told by the envelopes' stream and id.
+func (s *Server) ws(w http.ResponseWriter, r *http.Request) {
+	log := logger.S()
+
+	var streams *pubsub.StreamConfig
+
+	if s.Redis.IsStreams() {
+		streams = &pubsub.StreamConfig{
+			Group: r.URL.Query().Get("group"),
+			From:  make(map[string]string),
+		}
+
+		for _, from := range r.URL.Query()["from"] {
+			stream, id, ok := strings.Cut(from, ":")
+			if !ok || stream == "" || !streamIDPattern.MatchString(id) {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Bad stream entry %q, expected <stream>:<id>", from))
+				return
+			}
+
+			streams.From[stream] = id
+		}
+
+		if streams.Group != "" {
+			issued, err := pubsub.RenewGroup(r.Context(), s.Redis.Client, streams.Group)
+			if err != nil {
+				log.Errorf("Failed to renew consumer group: %s", err.Error())
+				writeError(w, http.StatusInternalServerError, "Failed to resume stream group")
+				return
+			}
+
+			if !issued {
+				writeError(w, http.StatusBadRequest, "Unknown or expired stream group")
+				return
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			attached, err := pubsub.AttachGroup(ctx, s.Redis.Client, streams.Group)
+			if err != nil {
+				log.Errorf("Failed to attach consumer group: %s", err.Error())
+				writeError(w, http.StatusInternalServerError, "Failed to resume stream group")
+				return
+			}
+
+			if !attached {
+				writeError(w, http.StatusConflict, "Stream group is being read by another connection")
+				return
+			}
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Errorf("Failed to upgrade connection: %s", err.Error())
+		return
+	}
+
+	manager := pubsub.NewSubscriptionManager(s.Redis.Client, conn, s.DB, s.Registry, streams)
+
+	defer func() {
+		manager.Close()
+
+		if err := conn.Close(); err != nil {
+			log.Errorf("Failed to close WebSocket connection: %s", err.Error())
+		}
+	}()
+
+	for {
+		var req pubsub.SubscriptionRequest
+
+		if err := conn.ReadJSON(&req); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Errorf("Failed to read from WebSocket connection: %s", err.Error())
+			}
+
+			return
+		}
+
+		if err := req.Parse(); err != nil {
+			manager.SendData(&pubsub.SubscriptionResponse{
+				Code: 0,
+				Msg:  fmt.Sprintf("Bad subscription: %s", err.Error()),
+			})
+			continue
+		}
+
+		switch req.Type {
+		case "subscribe":
+			manager.Subscribe(&req)
+		case "unsubscribe":
+			manager.Unsubscribe(&req)
+		default:
+			manager.SendData(&pubsub.SubscriptionResponse{
+				Code: 0,
+				Msg:  fmt.Sprintf("Bad request type %q", req.Type),
+			})
+		}
+	}
+}
+
+// StreamGroupResponse carries the name of a durable consumer group, which
+// is destroyed once it hasn't been read from for TTL seconds.
+type StreamGroupResponse struct {
+	Group string `json:"group"`
+	TTL   uint64 `json:"ttl"`
+}
+
+// streamGroup issues a durable consumer group, for WebSocket clients to
+// resume reading streams with.
+func (s *Server) streamGroup(w http.ResponseWriter, r *http.Request) {
+	if !s.Redis.IsStreams() {
+		writeError(w, http.StatusNotFound, "Topics aren't read from Redis Streams")
+		return
+	}
+
+	group, err := pubsub.IssueGroup(r.Context(), s.Redis.Client)
+	if err != nil {
+		logger.S().Errorf("Failed to issue consumer group: %s", err.Error())
+		writeError(w, http.StatusInternalServerError, "Failed to issue stream group")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &StreamGroupResponse{
+		Group: group,
+		TTL:   uint64(pubsub.GetGroupTTL().Seconds()),
+	})
+}
@@ -0,0 +1,78 @@
+package token
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+var (
+	// TransferTopic is topic0 of `Transfer(address,address,uint256)`
+	TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+	// ApprovalTopic is topic0 of `Approval(address,address,uint256)`
+	ApprovalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")).Hex()
+)
+
+// isERC20 tells whether the event carries two indexed addresses followed by
+// an amount. ERC-721 shares the same signatures but indexes the token id as
+// well, which gives it a fourth topic and no data.
+func isERC20(event *data.Event) bool {
+	return len(event.Topics) == 3 && len(event.Data) == 32
+}
+
+// topicToAddress unpacks an address left padded to 32 bytes in a topic.
+func topicToAddress(topic string) string {
+	return common.BytesToAddress(common.FromHex(topic)).Hex()
+}
+
+// BuildTokenTransfers picks ERC-20 Transfer events out of the given ones.
+func BuildTokenTransfers(events []*data.Event) []*data.TokenTransfer {
+	transfers := make([]*data.TokenTransfer, 0)
+
+	for _, event := range events {
+		if !isERC20(event) || event.Topics[0] != TransferTopic {
+			continue
+		}
+
+		transfers = append(transfers, &data.TokenTransfer{
+			TransactionHash: event.TransactionHash,
+			LogIndex:        event.Index,
+			Token:           event.Origin,
+			From:            topicToAddress(event.Topics[1]),
+			To:              topicToAddress(event.Topics[2]),
+			Value:           new(big.Int).SetBytes(event.Data).String(),
+			BlockHash:       event.BlockHash,
+			BlockNumber:     event.BlockNumber,
+			Timestamp:       event.Timestamp,
+		})
+	}
+
+	return transfers
+}
+
+// BuildTokenApprovals picks ERC-20 Approval events out of the given ones.
+func BuildTokenApprovals(events []*data.Event) []*data.TokenApproval {
+	approvals := make([]*data.TokenApproval, 0)
+
+	for _, event := range events {
+		if !isERC20(event) || event.Topics[0] != ApprovalTopic {
+			continue
+		}
+
+		approvals = append(approvals, &data.TokenApproval{
+			TransactionHash: event.TransactionHash,
+			LogIndex:        event.Index,
+			Token:           event.Origin,
+			Owner:           topicToAddress(event.Topics[1]),
+			Spender:         topicToAddress(event.Topics[2]),
+			Value:           new(big.Int).SetBytes(event.Data).String(),
+			BlockHash:       event.BlockHash,
+			BlockNumber:     event.BlockNumber,
+			Timestamp:       event.Timestamp,
+		})
+	}
+
+	return approvals
+}
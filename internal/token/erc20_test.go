@@ -0,0 +1,110 @@
+package token
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	c "github.com/kunalsinghdadhwal/nyx/internal/common"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+var (
+	contract = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	alice    = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	bob      = common.HexToAddress("0x00000000000000000000000000000000000000b0")
+	zero     = common.Address{}
+)
+
+// eventOf turns a log into the event it's stored as.
+func eventOf(log *types.Log) *data.Event {
+	return &data.Event{
+		Origin:          log.Address.Hex(),
+		Index:           log.Index,
+		Topics:          c.StringifyEventTopics(log.Topics),
+		Data:            log.Data,
+		TransactionHash: log.TxHash.Hex(),
+		BlockHash:       log.BlockHash.Hex(),
+		BlockNumber:     log.BlockNumber,
+	}
+}
+
+func addressTopic(address common.Address) common.Hash {
+	return common.BytesToHash(address.Bytes())
+}
+
+func word(n int64) []byte {
+	return common.LeftPadBytes(big.NewInt(n).Bytes(), 32)
+}
+
+func TestBuildTokenTransfers(t *testing.T) {
+	transfer := common.HexToHash(TransferTopic)
+	approval := common.HexToHash(ApprovalTopic)
+
+	tests := []struct {
+		name string
+		log  *types.Log
+		want []*data.TokenTransfer
+	}{
+		{
+			name: "transfer",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob)}, Data: word(1000)},
+			want: []*data.TokenTransfer{{Token: contract.Hex(), From: alice.Hex(), To: bob.Hex(), Value: "1000"}},
+		},
+		{
+			name: "mint",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(zero), addressTopic(bob)}, Data: word(5)},
+			want: []*data.TokenTransfer{{Token: contract.Hex(), From: zero.Hex(), To: bob.Hex(), Value: "5"}},
+		},
+		{
+			name: "burn",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(zero)}, Data: word(0)},
+			want: []*data.TokenTransfer{{Token: contract.Hex(), From: alice.Hex(), To: zero.Hex(), Value: "0"}},
+		},
+		{
+			// Same signature, but the token id is indexed as a fourth topic
+			name: "erc721 transfer",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob), common.BigToHash(big.NewInt(7))}},
+		},
+		{
+			name: "approval",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{approval, addressTopic(alice), addressTopic(bob)}, Data: word(1000)},
+		},
+		{
+			name: "unexpected data",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob)}, Data: append(word(1), word(2)...)},
+		},
+	}
+
+	for _, test := range tests {
+		got := BuildTokenTransfers([]*data.Event{eventOf(test.log)})
+
+		if len(got) != len(test.want) {
+			t.Errorf("%s: expected %d transfers, got %d", test.name, len(test.want), len(got))
+			continue
+		}
+
+		for i, want := range test.want {
+			if got[i].Token != want.Token || got[i].From != want.From || got[i].To != want.To || got[i].Value != want.Value {
+				t.Errorf("%s: expected %+v, got %+v", test.name, want, got[i])
+			}
+		}
+	}
+}
+
+func TestBuildTokenApprovals(t *testing.T) {
+	events := []*data.Event{
+		eventOf(&types.Log{Address: contract, Topics: []common.Hash{common.HexToHash(ApprovalTopic), addressTopic(alice), addressTopic(bob)}, Data: word(42)}),
+		eventOf(&types.Log{Address: contract, Topics: []common.Hash{common.HexToHash(TransferTopic), addressTopic(alice), addressTopic(bob)}, Data: word(42)}),
+	}
+
+	got := BuildTokenApprovals(events)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 approval, got %d", len(got))
+	}
+
+	if got[0].Owner != alice.Hex() || got[0].Spender != bob.Hex() || got[0].Value != "42" {
+		t.Errorf("unexpected approval %+v", got[0])
+	}
+}
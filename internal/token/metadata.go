@@ -0,0 +1,159 @@
+package token
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var stringType, _ = abi.NewType("string", "", nil)
+
+// ErrNotToken is returned for contracts which definitely aren't tokens, as
+// reading their decimals reverted or returned something else than a number.
+var ErrNotToken = errors.New("not a token")
+
+// isRevert tells whether a call failed because the contract reverted, rather
+// than because the node couldn't be reached or failed otherwise. Geth
+// reports reverts with error code 3, while other nodes only say so in the
+// message.
+func isRevert(err error) bool {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == 3 {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+func selector(signature string) []byte {
+	return crypto.Keccak256([]byte(signature))[:4]
+}
+
+func call(client *ethclient.Client, address common.Address, signature string) ([]byte, error) {
+	return client.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &address,
+		Data: selector(signature),
+	}, nil)
+}
+
+// decodeString reads a string returned by a contract. A few early tokens,
+// MKR being the best known, return their name and symbol as bytes32.
+func decodeString(out []byte) string {
+	if values, err := (abi.Arguments{{Type: stringType}}).Unpack(out); err == nil {
+		return values[0].(string)
+	}
+
+	if len(out) == 32 {
+		return string(bytes.TrimRight(out, "\x00"))
+	}
+
+	return ""
+}
+
+// FetchMetadata reads name, symbol and decimals off a token contract. Name
+// and symbol are optional in ERC-20, so they're left empty when a contract
+// doesn't implement them, but decimals are required to scale amounts: it
+// fails with ErrNotToken when they can't be read off the contract.
+func FetchMetadata(client *ethclient.Client, address string) (*data.Token, error) {
+	contract := common.HexToAddress(address)
+
+	out, err := call(client, contract, "decimals()")
+	if err != nil {
+		if isRevert(err) {
+			return nil, fmt.Errorf("failed to read decimals of %s: %w: %s", address, ErrNotToken, err.Error())
+		}
+
+		return nil, fmt.Errorf("failed to read decimals of %s: %w", address, err)
+	}
+
+	if len(out) != 32 || !bytes.Equal(out[:31], make([]byte, 31)) {
+		return nil, fmt.Errorf("failed to read decimals of %s: %w: unexpected return value", address, ErrNotToken)
+	}
+
+	token := &data.Token{
+		Address:  contract.Hex(),
+		Decimals: out[31],
+	}
+
+	if out, err := call(client, contract, "name()"); err == nil {
+		token.Name = decodeString(out)
+	}
+
+	if out, err := call(client, contract, "symbol()"); err == nil {
+		token.Symbol = decodeString(out)
+	}
+
+	return token, nil
+}
+
+// unresolved remembers contracts which turned out not to be tokens, so that
+// they aren't called again for every transfer they emit. Contracts which
+// couldn't be read for any other reason, e.g. the node being unreachable,
+// are tried again with their next transfer.
+var unresolved sync.Map
+
+// ResolveMetadata makes sure metadata of every token seen in the given
+// transfers is stored, fetching it from the node for new ones.
+func ResolveMetadata(client *ethclient.Client, _db *gorm.DB, transfers []*data.TokenTransfer) error {
+	addresses := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, transfer := range transfers {
+		if seen[transfer.Token] {
+			continue
+		}
+
+		seen[transfer.Token] = true
+
+		if _, ok := unresolved.Load(transfer.Token); !ok {
+			addresses = append(addresses, transfer.Token)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	known, err := db.GetTokensByAddress(_db, addresses)
+	if err != nil {
+		return err
+	}
+
+	tokens := make([]*data.Token, 0)
+
+	for _, address := range addresses {
+		if _, ok := known[address]; ok {
+			continue
+		}
+
+		token, err := FetchMetadata(client, address)
+		if err != nil {
+			if errors.Is(err, ErrNotToken) {
+				logger.S().Debugf("Skipping token metadata: %s", err.Error())
+				unresolved.Store(address, true)
+				continue
+			}
+
+			logger.S().Warnf("Failed to fetch token metadata, will retry: %s", err.Error())
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return db.StoreTokens(_db, tokens)
+}
@@ -0,0 +1,95 @@
+package token
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+var (
+	// TransferSingleTopic is topic0 of ERC-1155
+	// `TransferSingle(address,address,address,uint256,uint256)`
+	TransferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)")).Hex()
+	// TransferBatchTopic is topic0 of ERC-1155
+	// `TransferBatch(address,address,address,uint256[],uint256[])`
+	TransferBatchTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])")).Hex()
+)
+
+var uint256ArrayType, _ = abi.NewType("uint256[]", "", nil)
+
+// BuildNFTTransfers picks ERC-721 and ERC-1155 transfers out of the given
+// events, splitting batches into one transfer per token.
+func BuildNFTTransfers(events []*data.Event) []*data.NFTTransfer {
+	transfers := make([]*data.NFTTransfer, 0)
+
+	for _, event := range events {
+		if len(event.Topics) != 4 {
+			continue
+		}
+
+		switch event.Topics[0] {
+		case TransferTopic:
+			// ERC-721 indexes the token id, which leaves no data behind
+			if len(event.Data) != 0 {
+				continue
+			}
+
+			transfers = append(transfers, newNFTTransfer(event, data.ERC721, 0, "", event.Topics[1], event.Topics[2], new(big.Int).SetBytes(common.FromHex(event.Topics[3])), big.NewInt(1)))
+
+		case TransferSingleTopic:
+			if len(event.Data) != 64 {
+				continue
+			}
+
+			id := new(big.Int).SetBytes(event.Data[:32])
+			amount := new(big.Int).SetBytes(event.Data[32:])
+
+			transfers = append(transfers, newNFTTransfer(event, data.ERC1155, 0, event.Topics[1], event.Topics[2], event.Topics[3], id, amount))
+
+		case TransferBatchTopic:
+			values, err := (abi.Arguments{{Type: uint256ArrayType}, {Type: uint256ArrayType}}).Unpack(event.Data)
+			if err != nil {
+				continue
+			}
+
+			ids := values[0].([]*big.Int)
+			amounts := values[1].([]*big.Int)
+
+			if len(ids) != len(amounts) {
+				continue
+			}
+
+			for i := range ids {
+				transfers = append(transfers, newNFTTransfer(event, data.ERC1155, uint(i), event.Topics[1], event.Topics[2], event.Topics[3], ids[i], amounts[i]))
+			}
+		}
+	}
+
+	return transfers
+}
+
+func newNFTTransfer(event *data.Event, standard string, batchIndex uint, operator string, from string, to string, id *big.Int, amount *big.Int) *data.NFTTransfer {
+	transfer := &data.NFTTransfer{
+		TransactionHash: event.TransactionHash,
+		LogIndex:        event.Index,
+		BatchIndex:      batchIndex,
+		Standard:        standard,
+		Contract:        event.Origin,
+		TokenID:         id.String(),
+		From:            topicToAddress(from),
+		To:              topicToAddress(to),
+		Amount:          amount.String(),
+		BlockHash:       event.BlockHash,
+		BlockNumber:     event.BlockNumber,
+		Timestamp:       event.Timestamp,
+	}
+
+	if operator != "" {
+		transfer.Operator = topicToAddress(operator)
+	}
+
+	return transfer
+}
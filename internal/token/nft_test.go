@@ -0,0 +1,101 @@
+package token
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+func batchData(t *testing.T, ids []*big.Int, amounts []*big.Int) []byte {
+	packed, err := (abi.Arguments{{Type: uint256ArrayType}, {Type: uint256ArrayType}}).Pack(ids, amounts)
+	if err != nil {
+		t.Fatalf("failed to pack batch: %s", err)
+	}
+
+	return packed
+}
+
+func TestBuildNFTTransfers(t *testing.T) {
+	transfer := common.HexToHash(TransferTopic)
+	single := common.HexToHash(TransferSingleTopic)
+	batch := common.HexToHash(TransferBatchTopic)
+	operator := common.HexToAddress("0x00000000000000000000000000000000000000e0")
+
+	tests := []struct {
+		name string
+		log  *types.Log
+		want []*data.NFTTransfer
+	}{
+		{
+			name: "erc721 transfer",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob), common.BigToHash(big.NewInt(7))}},
+			want: []*data.NFTTransfer{{Standard: data.ERC721, From: alice.Hex(), To: bob.Hex(), TokenID: "7", Amount: "1"}},
+		},
+		{
+			name: "erc721 mint",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(zero), addressTopic(bob), common.BigToHash(big.NewInt(1))}},
+			want: []*data.NFTTransfer{{Standard: data.ERC721, From: zero.Hex(), To: bob.Hex(), TokenID: "1", Amount: "1"}},
+		},
+		{
+			// Same signature, but with the amount as data and no token id
+			name: "erc20 transfer",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob)}, Data: word(7)},
+		},
+		{
+			name: "erc721 transfer with data",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{transfer, addressTopic(alice), addressTopic(bob), common.BigToHash(big.NewInt(7))}, Data: word(1)},
+		},
+		{
+			name: "transfer single",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{single, addressTopic(operator), addressTopic(alice), addressTopic(bob)}, Data: append(word(3), word(25)...)},
+			want: []*data.NFTTransfer{{Standard: data.ERC1155, Operator: operator.Hex(), From: alice.Hex(), To: bob.Hex(), TokenID: "3", Amount: "25"}},
+		},
+		{
+			name: "transfer single burn",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{single, addressTopic(operator), addressTopic(alice), addressTopic(zero)}, Data: append(word(3), word(1)...)},
+			want: []*data.NFTTransfer{{Standard: data.ERC1155, Operator: operator.Hex(), From: alice.Hex(), To: zero.Hex(), TokenID: "3", Amount: "1"}},
+		},
+		{
+			name: "transfer single with short data",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{single, addressTopic(operator), addressTopic(alice), addressTopic(bob)}, Data: word(3)},
+		},
+		{
+			name: "transfer batch mint",
+			log: &types.Log{Address: contract, Topics: []common.Hash{batch, addressTopic(operator), addressTopic(zero), addressTopic(bob)},
+				Data: batchData(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10), big.NewInt(20)})},
+			want: []*data.NFTTransfer{
+				{Standard: data.ERC1155, BatchIndex: 0, Operator: operator.Hex(), From: zero.Hex(), To: bob.Hex(), TokenID: "1", Amount: "10"},
+				{Standard: data.ERC1155, BatchIndex: 1, Operator: operator.Hex(), From: zero.Hex(), To: bob.Hex(), TokenID: "2", Amount: "20"},
+			},
+		},
+		{
+			name: "transfer batch of different lengths",
+			log: &types.Log{Address: contract, Topics: []common.Hash{batch, addressTopic(operator), addressTopic(alice), addressTopic(bob)},
+				Data: batchData(t, []*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(10)})},
+		},
+		{
+			name: "transfer batch with bad data",
+			log:  &types.Log{Address: contract, Topics: []common.Hash{batch, addressTopic(operator), addressTopic(alice), addressTopic(bob)}, Data: word(1)},
+		},
+	}
+
+	for _, test := range tests {
+		got := BuildNFTTransfers([]*data.Event{eventOf(test.log)})
+
+		if len(got) != len(test.want) {
+			t.Errorf("%s: expected %d transfers, got %d", test.name, len(test.want), len(got))
+			continue
+		}
+
+		for i, want := range test.want {
+			if got[i].Contract != contract.Hex() || got[i].Standard != want.Standard || got[i].BatchIndex != want.BatchIndex || got[i].Operator != want.Operator ||
+				got[i].From != want.From || got[i].To != want.To || got[i].TokenID != want.TokenID || got[i].Amount != want.Amount {
+				t.Errorf("%s: expected %+v, got %+v", test.name, want, got[i])
+			}
+		}
+	}
+}
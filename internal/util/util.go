@@ -38,6 +38,19 @@ func IsValidAddress(iaddress interface{}) bool {
 	}
 }
 
+func IsValidHash(ihash interface{}) bool {
+	re := regexp.MustCompile("^0x[0-9a-fA-F]{64}$")
+
+	switch v := ihash.(type) {
+	case common.Hash:
+		return re.MatchString(v.Hex())
+	case string:
+		return re.MatchString(v)
+	default:
+		return false
+	}
+}
+
 func IsZeroAddress(iaddress interface{}) bool {
 	var address common.Address
 
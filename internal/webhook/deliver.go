@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+)
+
+// Headers every delivery is sent with. The signature is an HMAC-SHA256, keyed
+// by the webhook's secret, of the timestamp and the body joined by a dot, so
+// that receivers can tell a delivery is genuine and turn down replayed ones.
+const (
+	SignatureHeader = "X-Nyx-Signature"
+	TimestampHeader = "X-Nyx-Timestamp"
+	DeliveryHeader  = "X-Nyx-Delivery"
+)
+
+// deliveryBatchSize is how many due deliveries are attempted at once.
+const deliveryBatchSize = 64
+
+// Sign computes the signature of a delivery made at the given time.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// nextDelay grows the delay between attempts by the golden ratio, the way
+// failed blocks are retried, wrapping around after an hour.
+func nextDelay(delay uint64) uint64 {
+	next := uint64(math.Round(float64(delay)*(1.0+math.Sqrt(5.0))/2)) % 3600
+	if next == 0 {
+		return 1
+	}
+
+	return next
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	defer close(d.done)
+
+	for {
+		if d.deliverDue(ctx) < deliveryBatchSize {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(1) * time.Second):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// deliverDue attempts a batch of deliveries which are due, returning how many
+// attempts were made. Deliveries whose webhook is gone are deleted.
+func (d *Dispatcher) deliverDue(ctx context.Context) int {
+	log := logger.S()
+
+	deliveries, err := db.ClaimDueWebhookDeliveries(d.DB, time.Now().UTC(), d.Lease, deliveryBatchSize)
+	if err != nil {
+		log.Errorf("Failed to query due webhook deliveries: %s", err.Error())
+		return 0
+	}
+
+	if len(deliveries) == 0 {
+		return 0
+	}
+
+	webhooks, err := db.GetWebhooks(d.DB)
+	if err != nil {
+		log.Errorf("Failed to query webhooks: %s", err.Error())
+		return 0
+	}
+
+	byID := make(map[uint]*data.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		byID[webhook.ID] = webhook
+	}
+
+	var wg sync.WaitGroup
+
+	orphaned := make([]uint, 0)
+	attempted := 0
+
+	for _, delivery := range deliveries {
+		webhook, ok := byID[delivery.WebhookID]
+		if !ok {
+			orphaned = append(orphaned, delivery.ID)
+			continue
+		}
+
+		attempted++
+		wg.Add(1)
+
+		go func(webhook *data.Webhook, delivery *data.WebhookDelivery) {
+			defer wg.Done()
+
+			d.attempt(ctx, webhook, delivery)
+		}(webhook, delivery)
+	}
+
+	if err := db.DeleteWebhookDeliveries(d.DB, orphaned); err != nil {
+		log.Errorf("Failed to delete deliveries of deleted webhooks: %s", err.Error())
+	}
+
+	wg.Wait()
+
+	return attempted
+}
+
+// attempt makes a delivery and records how it went, scheduling another
+// attempt or giving up once it's been tried too many times.
+func (d *Dispatcher) attempt(ctx context.Context, webhook *data.Webhook, delivery *data.WebhookDelivery) {
+	log := logger.S()
+
+	code, err := d.post(ctx, webhook, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down, so it's attempted again on the next start
+		return
+	}
+
+	delivery.Attempts++
+	delivery.ResponseCode = code
+
+	if err == nil {
+		delivery.Status = data.DeliveryDelivered
+		delivery.Error = ""
+
+		if err := db.UpdateWebhookDelivery(d.DB, delivery); err != nil {
+			log.Errorf("Failed to record webhook delivery %d: %s", delivery.ID, err.Error())
+		}
+		return
+	}
+
+	delivery.Error = err.Error()
+
+	if delivery.Attempts >= d.MaxAttempts {
+		log.Warnf("Giving up on webhook delivery %d to %s after %d attempts: %s", delivery.ID, webhook.URL, delivery.Attempts, delivery.Error)
+
+		if err := db.DeadLetterWebhookDelivery(d.DB, delivery); err != nil {
+			log.Errorf("Failed to dead letter webhook delivery %d: %s", delivery.ID, err.Error())
+		}
+		return
+	}
+
+	delivery.Delay = nextDelay(delivery.Delay)
+	delivery.NextAttemptAt = time.Now().UTC().Add(time.Duration(delivery.Delay) * time.Second)
+
+	if err := db.UpdateWebhookDelivery(d.DB, delivery); err != nil {
+		log.Errorf("Failed to record webhook delivery %d: %s", delivery.ID, err.Error())
+	}
+}
+
+// post sends a delivery, which counts as delivered on any 2xx response.
+func (d *Dispatcher) post(ctx context.Context, webhook *data.Webhook, delivery *data.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "nyx-webhook")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, payload))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
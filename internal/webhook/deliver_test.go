@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+)
+
+func TestNextDelayFollowsGoldenRatio(t *testing.T) {
+	expected := []uint64{2, 3, 5, 8, 13, 21, 34, 55, 89}
+
+	delay := uint64(1)
+	for _, e := range expected {
+		delay = nextDelay(delay)
+
+		if delay != e {
+			t.Fatalf("delay is %d, expected %d", delay, e)
+		}
+	}
+}
+
+func TestPostSignsDelivery(t *testing.T) {
+	hook := &data.Webhook{Secret: "s3cret"}
+	delivery := &data.WebhookDelivery{ID: 7, Payload: `{"topic":"block"}`}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if err != nil {
+			t.Errorf("bad timestamp header %q", r.Header.Get(TimestampHeader))
+		}
+
+		if r.Header.Get(SignatureHeader) != Sign(hook.Secret, timestamp, body) {
+			t.Errorf("signature %q doesn't match the body", r.Header.Get(SignatureHeader))
+		}
+
+		if r.Header.Get(DeliveryHeader) != "7" {
+			t.Errorf("delivery header is %q, expected 7", r.Header.Get(DeliveryHeader))
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	hook.URL = server.URL
+
+	d := &Dispatcher{Client: server.Client()}
+
+	code, err := d.post(context.Background(), hook, delivery)
+	if err != nil || code != http.StatusAccepted {
+		t.Fatalf("delivery failed with status %d: %v", code, err)
+	}
+}
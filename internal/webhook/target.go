@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget is returned for webhook URLs pointing at loopback, private
+// or link-local addresses, which the indexer mustn't be made to reach.
+var ErrPrivateTarget = errors.New("webhook target isn't a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't covered by
+// net.IP.IsPrivate.
+var sharedAddressSpace = &net.IPNet{IP: net.IP{100, 64, 0, 0}, Mask: net.CIDRMask(10, 32)}
+
+// allowPrivateTargets tells whether WEBHOOK_ALLOW_PRIVATE_TARGETS lets
+// webhooks point at internal addresses, e.g. while developing locally.
+func allowPrivateTargets() bool {
+	allow, err := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_TARGETS"))
+	return err == nil && allow
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// CheckURL validates the URL of a webhook being registered, resolving its
+// host to make sure every address it points at is public. Deliveries check
+// the address again as they connect, as DNS may have changed since.
+func CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("expected an http or https URL")
+	}
+
+	if allowPrivateTargets() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(5)*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", u.Hostname(), err)
+	}
+
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return ErrPrivateTarget
+		}
+	}
+
+	return nil
+}
+
+// dialControl turns down connections to internal addresses, once the host
+// of a delivery, or of any redirect it's sent to, has been resolved.
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return ErrPrivateTarget
+	}
+
+	return nil
+}
+
+// newClient returns the HTTP client deliveries are POSTed with.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: time.Duration(30) * time.Second,
+	}
+
+	if !allowPrivateTargets() {
+		dialer.Control = dialControl
+	}
+
+	// Going through a proxy would have the proxy's address checked instead
+	// of the webhook's
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
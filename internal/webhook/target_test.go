@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckURLRejectsPrivateTargets(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "")
+
+	for _, raw := range []string{
+		"http://127.0.0.1/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://100.64.0.1/hook",
+	} {
+		if err := CheckURL(context.Background(), raw); !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("%s was accepted (%v)", raw, err)
+		}
+	}
+
+	if err := CheckURL(context.Background(), "https://93.184.215.14/hook"); err != nil {
+		t.Errorf("public address was turned down: %v", err)
+	}
+
+	if err := CheckURL(context.Background(), "ftp://93.184.215.14/hook"); err == nil {
+		t.Error("non-HTTP URL was accepted")
+	}
+}
+
+func TestDialControlRejectsPrivateTargets(t *testing.T) {
+	if err := dialControl("tcp", "169.254.169.254:80", nil); !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("link-local address was dialed (%v)", err)
+	}
+
+	if err := dialControl("tcp", "93.184.215.14:443", nil); err != nil {
+		t.Errorf("public address was turned down: %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kunalsinghdadhwal/nyx/internal/data"
+	"github.com/kunalsinghdadhwal/nyx/internal/db"
+	"github.com/kunalsinghdadhwal/nyx/internal/pubsub"
+	"github.com/kunalsinghdadhwal/nyx/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Dispatcher is a publisher queueing data which matches registered webhooks
+// for delivery, as the indexer publishes it. Queued deliveries are POSTed in
+// the background, until the dispatcher is closed. Several dispatchers, e.g.
+// of an indexer and a backfill, share the queue: each claims the deliveries
+// it attempts for Lease, long enough for an attempt to time out.
+type Dispatcher struct {
+	DB          *gorm.DB
+	Client      *http.Client
+	MaxAttempts uint
+	Lease       time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func getMaxAttempts() uint {
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.ParseUint(v, 10, 32)
+		if err == nil && attempts > 0 {
+			return uint(attempts)
+		}
+	}
+
+	return 10
+}
+
+func getTimeout() time.Duration {
+	if v := os.Getenv("WEBHOOK_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err == nil {
+			return timeout
+		}
+	}
+
+	return time.Duration(10) * time.Second
+}
+
+// New starts delivering queued webhook deliveries.
+func New(_db *gorm.DB) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Dispatcher{
+		DB:          _db,
+		Client:      newClient(getTimeout()),
+		MaxAttempts: getMaxAttempts(),
+		Lease:       getTimeout() + time.Duration(1)*time.Minute,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	go d.run(ctx)
+
+	return d
+}
+
+// NewSecret generates the key deliveries to a webhook are signed with.
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// Subscription parses the subscription of a webhook, along with its filter
+// object if it has one, and the finality it's after.
+func Subscription(webhook *data.Webhook) (*pubsub.SubscriptionRequest, error) {
+	req := &pubsub.SubscriptionRequest{
+		Name:     webhook.Subscription,
+		Type:     "subscribe",
+		Finality: webhook.Finality,
+	}
+
+	if webhook.Filter != "" {
+		req.Filter = &pubsub.FilterObject{}
+
+		if err := json.Unmarshal([]byte(webhook.Filter), req.Filter); err != nil {
+			return nil, fmt.Errorf("bad filter: %w", err)
+		}
+	}
+
+	if err := req.Parse(); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// matches tells whether the published message is one the subscription is
+// after, published with the finality it asked for.
+func matches(req *pubsub.SubscriptionRequest, msg *data.Message) bool {
+	if req.Topic() != msg.Topic {
+		return false
+	}
+
+	finality := msg.Finality
+	if finality == "" {
+		finality = data.FinalityLatest
+	}
+
+	if req.GetFinality() != finality {
+		return false
+	}
+
+	switch v := msg.Data.(type) {
+	case *data.Transaction:
+		return req.DoesMatchWithPublishedTransactionData(v)
+	case *data.Event:
+		return req.DoesMatchWithPublishedEventData(v)
+	case *data.Withdrawal:
+		return req.DoesMatchWithPublishedWithdrawalData(v)
+	}
+
+	return true
+}
+
+// Publish queues a delivery of every message to each webhook it matches.
+func (d *Dispatcher) Publish(ctx context.Context, messages ...*data.Message) error {
+	webhooks, err := db.GetWebhooks(d.DB)
+	if err != nil {
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	subscriptions := make(map[uint]*pubsub.SubscriptionRequest, len(webhooks))
+
+	for _, webhook := range webhooks {
+		req, err := Subscription(webhook)
+		if err != nil {
+			logger.S().Errorf("Skipping webhook %d with bad subscription: %s", webhook.ID, err.Error())
+			continue
+		}
+
+		subscriptions[webhook.ID] = req
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]*data.WebhookDelivery, 0)
+
+	for _, msg := range messages {
+		var payload []byte
+
+		for _, webhook := range webhooks {
+			req, ok := subscriptions[webhook.ID]
+			if !ok || !matches(req, msg) {
+				continue
+			}
+
+			if payload == nil {
+				if payload, err = msg.Data.MarshalBinary(); err != nil {
+					return err
+				}
+			}
+
+			deliveries = append(deliveries, &data.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				Topic:         msg.Topic,
+				MessageID:     msg.ID,
+				Payload:       fmt.Sprintf(`{"webhookId":%d,"topic":%q,"id":%q,"finality":%q,"confirmations":%d,"data":%s}`, webhook.ID, msg.Topic, msg.ID, req.GetFinality(), msg.Confirmations, payload),
+				Status:        data.DeliveryPending,
+				Delay:         1,
+				NextAttemptAt: now,
+			})
+		}
+	}
+
+	return db.StoreWebhookDeliveries(d.DB, deliveries)
+}
+
+// Close stops delivering, waiting for attempts in flight to be recorded.
+func (d *Dispatcher) Close() error {
+	d.cancel()
+	<-d.done
+
+	return nil
+}